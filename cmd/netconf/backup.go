@@ -0,0 +1,78 @@
+// Go NETCONF Client - Command Line Tool
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Juniper/go-netconf/netconf/backup"
+)
+
+// runBackup backs up the configuration of the devices of an inventory file
+// to a directory, printing the outcome of each device to out
+func runBackup(opts *options, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	formats := fs.String("formats", backup.FormatXML, "comma separated formats: xml, text or set")
+	git := fs.Bool("git", false, "commit the files to the git working tree of the directory")
+	message := fs.String("message", "", "git commit message")
+	concurrency := fs.Int("concurrency", 50, "maximum number of devices backed up at once")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: netconf [flags] backup [backup flags] <inventory> <dir>\n\nbackup flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected an inventory file and a directory")
+	}
+
+	devices, err := backup.LoadInventory(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := opts.readPassword(); err != nil {
+		return err
+	}
+
+	b := backup.New(fs.Arg(1), opts.dialHost)
+	b.Formats = strings.Split(*formats, ",")
+	b.Git = *git
+	b.Message = *message
+	b.Concurrency = *concurrency
+	b.Timeout = opts.timeout
+
+	report, err := b.Run(context.Background(), devices)
+	if report != nil {
+		for _, r := range report.Results {
+			switch {
+			case r.Err != nil:
+				fmt.Fprintf(out, "failed     %s: %v\n", r.Device.Name, r.Err)
+			case r.Changed:
+				fmt.Fprintf(out, "changed    %s\n", r.Device.Name)
+			default:
+				fmt.Fprintf(out, "unchanged  %s\n", r.Device.Name)
+			}
+		}
+		if report.Commit != "" {
+			fmt.Fprintf(out, "committed  %s\n", report.Commit)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if failed := len(report.Failed()); failed > 0 {
+		return fmt.Errorf("%d of %d devices failed", failed, len(devices))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// Go NETCONF Client - Command Line Tool
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A closed port refuses the connection
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	inventory := filepath.Join(dir, "inventory")
+	if err := ioutil.WriteFile(inventory, []byte("r1 "+addr+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &options{transport: "ssh", insecure: true, timeout: time.Second}
+	var out bytes.Buffer
+	err = runBackup(opts, []string{"-formats", "xml,text", inventory, filepath.Join(dir, "backups")}, &out)
+	if err == nil || err.Error() != "1 of 1 devices failed" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "failed     r1: ") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	err = runBackup(opts, []string{inventory}, ioutil.Discard)
+	if err == nil || err.Error() != "expected an inventory file and a directory" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+// Go NETCONF Client - Command Line Tool
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/Juniper/go-netconf/netconf"
+)
+
+// client runs commands against a session, printing the replies to out
+type client struct {
+	session *netconf.Session
+	out     io.Writer
+	// in is read by arguments given as -
+	in     io.Reader
+	format string
+}
+
+// command is a command of the tool, run from the command line or the REPL
+type command struct {
+	name  string
+	usage string
+	help  string
+	// minArgs and maxArgs bound the number of arguments, the last one
+	// taking the rest of a REPL line
+	minArgs int
+	maxArgs int
+	run     func(c *client, args []string) error
+}
+
+// commands are the commands of the tool, the repl and backup having no run
+// function as they are only available from the command line
+var commands = []*command{
+	{"hello", "hello", "print the session-id and server capabilities", 0, 0, (*client).hello},
+	{"get", "get [filter]", "get state and configuration data", 0, 1, (*client).get},
+	{"get-config", "get-config [source] [filter]", "get the configuration of source, running by default", 0, 2, (*client).getConfig},
+	{"edit-config", "edit-config <target> <config>", "edit the configuration of target", 2, 2, (*client).editConfig},
+	{"lock", "lock [target]", "lock target, candidate by default", 0, 1, (*client).lock},
+	{"unlock", "unlock [target]", "unlock target, candidate by default", 0, 1, (*client).unlock},
+	{"commit", "commit", "commit the candidate configuration", 0, 0, (*client).commit},
+	{"raw", "raw <rpc>", "send the rpc as-is", 1, 1, (*client).raw},
+	{"repl", "repl", "run commands interactively", 0, 0, nil},
+	{"backup", "backup <inventory> <dir>", "back up the configuration of many devices", 2, 2, nil},
+}
+
+// lookupCommand returns the command called name, nil if there is none or
+// it is only available from the command line
+func lookupCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name && c.run != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// run runs cmd with args after checking their number
+func (c *client) run(cmd *command, args []string) error {
+	if len(args) < cmd.minArgs || len(args) > cmd.maxArgs {
+		return fmt.Errorf("usage: %s", cmd.usage)
+	}
+	return cmd.run(c, args)
+}
+
+// input returns the content of an argument: the content of file for @file,
+// standard input for - or the argument itself
+func (c *client) input(arg string) (string, error) {
+	switch {
+	case arg == "-":
+		b, err := ioutil.ReadAll(c.in)
+		return string(b), err
+	case strings.HasPrefix(arg, "@"):
+		b, err := ioutil.ReadFile(arg[1:])
+		return string(b), err
+	}
+	return arg, nil
+}
+
+// exec executes method and prints its reply, printed even when it holds
+// rpc-errors
+func (c *client) exec(method netconf.RPCMethod) error {
+	reply, err := c.session.Exec(method)
+	if reply != nil {
+		if perr := c.print(reply.RawReply); perr != nil {
+			return perr
+		}
+	}
+	return err
+}
+
+// print prints an XML message in the client format
+func (c *client) print(msg string) error {
+	switch c.format {
+	case "json":
+		return printJSON(c.out, msg)
+	case "raw":
+		_, err := fmt.Fprintln(c.out, strings.TrimSpace(msg))
+		return err
+	}
+	return printXML(c.out, msg)
+}
+
+func (c *client) hello(args []string) error {
+	if c.format == "json" {
+		b, err := json.MarshalIndent(struct {
+			SessionID    int      `json:"session-id"`
+			Capabilities []string `json:"capabilities"`
+		}{c.session.SessionID, c.session.ServerCapabilities}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.out, "%s\n", b)
+		return err
+	}
+
+	fmt.Fprintf(c.out, "session-id: %d\ncapabilities:\n", c.session.SessionID)
+	for _, capability := range c.session.ServerCapabilities {
+		fmt.Fprintf(c.out, "  %s\n", strings.TrimSpace(capability))
+	}
+	return nil
+}
+
+func (c *client) get(args []string) error {
+	if len(args) == 0 {
+		return c.exec(netconf.RawMethod("<get/>"))
+	}
+	filter, err := c.input(args[0])
+	if err != nil {
+		return err
+	}
+	return c.exec(netconf.MethodGet("subtree", filter))
+}
+
+func (c *client) getConfig(args []string) error {
+	source := "running"
+	// The source can be omitted in front of a filter
+	if len(args) > 0 && !isData(args[0]) {
+		source, args = args[0], args[1:]
+	}
+	switch len(args) {
+	case 0:
+		return c.exec(netconf.MethodGetConfig(source))
+	case 2:
+		return fmt.Errorf("usage: get-config [source] [filter]")
+	}
+
+	filter, err := c.input(args[0])
+	if err != nil {
+		return err
+	}
+	return c.exec(netconf.MethodGetConfigFilter(source, "subtree", filter))
+}
+
+func (c *client) editConfig(args []string) error {
+	config, err := c.input(args[1])
+	if err != nil {
+		return err
+	}
+	return c.exec(netconf.MethodEditConfig(args[0], config))
+}
+
+func (c *client) lock(args []string) error {
+	return c.exec(netconf.MethodLock(target(args)))
+}
+
+func (c *client) unlock(args []string) error {
+	return c.exec(netconf.MethodUnlock(target(args)))
+}
+
+func (c *client) commit(args []string) error {
+	return c.exec(netconf.MethodCommit())
+}
+
+func (c *client) raw(args []string) error {
+	rpc, err := c.input(args[0])
+	if err != nil {
+		return err
+	}
+	return c.exec(netconf.RawMethod(rpc))
+}
+
+// target returns the datastore given in args, candidate by default
+func target(args []string) string {
+	if len(args) == 0 {
+		return "candidate"
+	}
+	return args[0]
+}
+
+// isData reports whether arg is XML data rather than a name
+func isData(arg string) bool {
+	return arg == "-" || strings.HasPrefix(arg, "@") || strings.HasPrefix(arg, "<")
+}
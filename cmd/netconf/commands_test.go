@@ -0,0 +1,165 @@
+// Go NETCONF Client - Command Line Tool
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Juniper/go-netconf/netconf"
+	"github.com/Juniper/go-netconf/netconf/netconftest"
+	"github.com/google/go-cmp/cmp"
+)
+
+// newClientTest returns a client of a fake server printing raw replies
+func newClientTest(t *testing.T) (*client, *netconftest.Server, *bytes.Buffer) {
+	server := netconftest.NewServer()
+	server.Handle(netconftest.MatchName("get-config"), netconftest.ReplyData("<system/>"))
+	server.Handle(netconftest.MatchName("lock"), netconftest.ReplyError(netconf.RPCError{
+		Type:     "protocol",
+		Tag:      "lock-denied",
+		Severity: "error",
+		Message:  "locked",
+	}))
+	server.Handle(netconftest.MatchAny(), netconftest.ReplyOK())
+
+	out := new(bytes.Buffer)
+	c := &client{session: server.Session(), out: out, format: "raw"}
+	return c, server, out
+}
+
+func TestCommands(t *testing.T) {
+	tt := []struct {
+		args    []string
+		in      string
+		request string
+		err     string
+	}{
+		{[]string{"get"}, "", "<get/>", ""},
+		{[]string{"get", "<system/>"}, "", `<get><filter type="subtree"><system/></filter></get>`, ""},
+		{[]string{"get-config"}, "", "<get-config><source><running/></source></get-config>", ""},
+		{[]string{"get-config", "candidate", "<system/>"}, "", `<get-config><source><candidate/></source><filter type="subtree"><system/></filter></get-config>`, ""},
+		{[]string{"get-config", "<system/>"}, "", `<get-config><source><running/></source><filter type="subtree"><system/></filter></get-config>`, ""},
+		{[]string{"edit-config", "candidate", "-"}, "<system><host-name>r1</host-name></system>", "<config><system><host-name>r1</host-name></system></config>", ""},
+		{[]string{"edit-config", "candidate"}, "", "", "usage: edit-config <target> <config>"},
+		{[]string{"lock"}, "", "<lock><target><candidate/></target></lock>", "netconf rpc [error] 'locked'"},
+		{[]string{"unlock", "running"}, "", "<unlock><target><running/></target></unlock>", ""},
+		{[]string{"commit"}, "", "<commit/>", ""},
+		{[]string{"raw", "<get-software-information/>"}, "", "<get-software-information/>", ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			c, server, _ := newClientTest(t)
+			defer server.Close()
+			c.in = strings.NewReader(tc.in)
+
+			err := c.run(lookupCommand(tc.args[0]), tc.args[1:])
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("got error %v, expected %s", err, tc.err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.request == "" {
+				if n := len(server.Requests()); n != 0 {
+					t.Errorf("got %d requests, expected none", n)
+				}
+				return
+			}
+			server.AssertReceived(t, netconftest.MatchRegexp(regexp.QuoteMeta(tc.request)))
+		})
+	}
+}
+
+func TestCommandPrintsErrorReply(t *testing.T) {
+	c, server, out := newClientTest(t)
+	defer server.Close()
+
+	if err := c.run(lookupCommand("lock"), nil); err == nil {
+		t.Fatalf("expected error")
+	}
+	if !strings.Contains(out.String(), "<error-tag>lock-denied</error-tag>") {
+		t.Errorf("error reply not printed: %s", out)
+	}
+}
+
+func TestHello(t *testing.T) {
+	c, server, out := newClientTest(t)
+	defer server.Close()
+
+	if err := c.run(lookupCommand("hello"), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "session-id: 1\ncapabilities:\n  urn:ietf:params:netconf:base:1.0\n"
+	if out.String() != expected {
+		t.Errorf("got %q, expected %q", out, expected)
+	}
+
+	out.Reset()
+	c.format = "json"
+	if err := c.run(lookupCommand("hello"), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = "{\n  \"session-id\": 1,\n  \"capabilities\": [\n    \"urn:ietf:params:netconf:base:1.0\"\n  ]\n}\n"
+	if out.String() != expected {
+		t.Errorf("got %q, expected %q", out, expected)
+	}
+}
+
+func TestReplLoop(t *testing.T) {
+	c, server, out := newClientTest(t)
+	defer server.Close()
+
+	input := "commit\n\nget-config running <system> <host-name/></system>\nbogus\nhistory\n!1\n!9\nquit\ncommit\n"
+	r := bufio.NewReader(strings.NewReader(input))
+	if err := c.replLoop(func() (string, error) { return r.ReadString('\n') }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := len(server.Received(netconftest.MatchName("commit"))); n != 2 {
+		t.Errorf("got %d commits, expected 2", n)
+	}
+	server.AssertReceived(t, netconftest.MatchXPath("get-config/filter/system/host-name"))
+
+	for _, s := range []string{
+		`error: unknown command "bogus", try help`,
+		"    1  commit\n    2  get-config running <system> <host-name/></system>\n    3  bogus\n    4  history\n",
+		"error: no history entry 9",
+	} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("output does not contain %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tt := []struct {
+		s        string
+		n        int
+		expected []string
+	}{
+		{"", 2, nil},
+		{"candidate", 1, []string{"candidate"}},
+		{"running  <a> <b/> </a>", 2, []string{"running", "<a> <b/> </a>"}},
+		{"<a> <b/> </a>", 2, []string{"<a> <b/> </a>"}},
+		{"candidate @edit.xml", 2, []string{"candidate", "@edit.xml"}},
+		{"a b c", 2, []string{"a", "b c"}},
+		{"a b", 0, []string{"a", "b"}},
+	}
+
+	for _, tc := range tt {
+		if diff := cmp.Diff(tc.expected, splitArgs(tc.s, tc.n)); diff != "" {
+			t.Errorf("%q: mismatch (-want +got):\n%s", tc.s, diff)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Go NETCONF Client - Command Line Tool
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Juniper/go-netconf/netconf"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+// options are the flags describing how to reach the server
+type options struct {
+	transport  string
+	host       string
+	port       int
+	user       string
+	password   string
+	askPass    bool
+	identity   string
+	knownHosts string
+	insecure   bool
+	cert       string
+	key        string
+	ca         string
+	timeout    time.Duration
+	format     string
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.transport, "transport", "ssh", "transport: ssh, tls or junos")
+	fs.StringVar(&o.host, "host", "localhost", "server host")
+	fs.IntVar(&o.port, "port", 0, "server port (default 830 for ssh, 6513 for tls)")
+	fs.StringVar(&o.user, "user", os.Getenv("USER"), "ssh username")
+	fs.StringVar(&o.password, "password", "", "ssh password (default $NETCONF_PASSWORD)")
+	fs.BoolVar(&o.askPass, "ask-pass", false, "prompt for the ssh password")
+	fs.StringVar(&o.identity, "identity", "", "ssh private key file, the ssh agent is used when unset")
+	fs.StringVar(&o.knownHosts, "known-hosts", "", "ssh known_hosts file (default ~/.ssh/known_hosts)")
+	fs.BoolVar(&o.insecure, "insecure", false, "do not verify the server host key or certificate")
+	fs.StringVar(&o.cert, "cert", "", "tls client certificate file")
+	fs.StringVar(&o.key, "key", "", "tls client private key file")
+	fs.StringVar(&o.ca, "ca", "", "tls CA certificates file (default system roots)")
+	fs.DurationVar(&o.timeout, "timeout", 30*time.Second, "connection timeout")
+	fs.StringVar(&o.format, "format", "xml", "reply format: xml, json or raw")
+}
+
+// dial establishes the session described by the options
+func (o *options) dial() (*netconf.Session, error) {
+	if o.format != "xml" && o.format != "json" && o.format != "raw" {
+		return nil, fmt.Errorf("unknown format %q", o.format)
+	}
+	if err := o.readPassword(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+	return o.dialHost(ctx, o.host)
+}
+
+// readPassword sets the ssh password from the environment or the terminal
+// when not given
+func (o *options) readPassword() error {
+	if o.password == "" {
+		o.password = os.Getenv("NETCONF_PASSWORD")
+	}
+	if !o.askPass || o.transport != "ssh" {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "%s's password: ", o.user)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return err
+	}
+	o.password = string(password)
+	return nil
+}
+
+// dialHost establishes a session with host, which may include a port, once
+// readPassword was called
+func (o *options) dialHost(ctx context.Context, host string) (*netconf.Session, error) {
+	switch o.transport {
+	case "ssh":
+		return o.dialSSH(ctx, host)
+	case "tls":
+		return o.dialTLS(ctx, host)
+	case "junos":
+		return netconf.DialJunos()
+	}
+	return nil, fmt.Errorf("unknown transport %q", o.transport)
+}
+
+// target returns the address of host, using the port option or defaultPort
+// when host has no port
+func (o *options) target(host string, defaultPort int) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	port := o.port
+	if port == 0 {
+		port = defaultPort
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+func (o *options) dialSSH(ctx context.Context, host string) (*netconf.Session, error) {
+	auth := netconf.SSHAuthOptions{Password: o.password}
+	if o.identity != "" {
+		signers, err := netconf.SSHSignersFromFile(o.identity, "")
+		if err != nil {
+			return nil, err
+		}
+		auth.Signers = signers
+	} else if os.Getenv("SSH_AUTH_SOCK") != "" {
+		// The agent is only needed while authenticating
+		if a, err := netconf.NewSSHAgent(); err == nil {
+			defer a.Close()
+			auth.Signers, _ = a.Signers()
+		}
+	}
+
+	config := netconf.SSHConfigMultiAuth(o.user, auth)
+	config.Timeout = o.timeout
+	if o.insecure {
+		config.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+	} else {
+		var files []string
+		if o.knownHosts != "" {
+			files = append(files, o.knownHosts)
+		}
+		callback, err := netconf.KnownHostsCallback(files...)
+		if err != nil {
+			return nil, err
+		}
+		config.HostKeyCallback = callback
+	}
+
+	return netconf.DialSSHContext(ctx, o.target(host, 830), config)
+}
+
+func (o *options) dialTLS(ctx context.Context, host string) (*netconf.Session, error) {
+	target := o.target(host, 6513)
+	serverName, _, _ := net.SplitHostPort(target)
+	config := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: o.insecure,
+	}
+
+	if o.cert != "" {
+		key := o.key
+		if key == "" {
+			key = o.cert
+		}
+		cert, err := tls.LoadX509KeyPair(o.cert, key)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if o.ca != "" {
+		pem, err := ioutil.ReadFile(o.ca)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.ca)
+		}
+	}
+
+	return netconf.DialTLSContext(ctx, target, config)
+}
@@ -0,0 +1,214 @@
+// Go NETCONF Client - Command Line Tool
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// element is an XML element, names keeping their prefix
+type element struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*element
+	text     string
+}
+
+// parseXML parses the root element of msg
+func parseXML(msg string) (*element, error) {
+	d := xml.NewDecoder(strings.NewReader(msg))
+	var root *element
+	var stack []*element
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			e := &element{name: tok.Name, attrs: tok.Copy().Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, e)
+			} else if root == nil {
+				root = e
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unexpected end element %s", tok.Name.Local)
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(tok)
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("no XML element found")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unexpected end of XML")
+	}
+	return root, nil
+}
+
+// qualified returns the name with its prefix
+func qualified(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// printXML prints msg indented, whitespace between elements being dropped
+func printXML(w io.Writer, msg string) error {
+	root, err := parseXML(msg)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writeXML(&buf, root, "")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeXML(buf *bytes.Buffer, e *element, indent string) {
+	buf.WriteString(indent + "<" + qualified(e.name))
+	for _, a := range e.attrs {
+		buf.WriteString(" " + qualified(a.Name) + `="`)
+		xml.EscapeText(buf, []byte(a.Value))
+		buf.WriteString(`"`)
+	}
+
+	text := strings.TrimSpace(e.text)
+	switch {
+	case len(e.children) == 0 && text == "":
+		buf.WriteString("/>\n")
+		return
+	case len(e.children) == 0:
+		buf.WriteString(">")
+		xml.EscapeText(buf, []byte(text))
+		buf.WriteString("</" + qualified(e.name) + ">\n")
+		return
+	}
+
+	buf.WriteString(">\n")
+	if text != "" {
+		buf.WriteString(indent + "  ")
+		xml.EscapeText(buf, []byte(text))
+		buf.WriteString("\n")
+	}
+	for _, child := range e.children {
+		writeXML(buf, child, indent+"  ")
+	}
+	buf.WriteString(indent + "</" + qualified(e.name) + ">\n")
+}
+
+// printJSON prints msg as JSON: elements become objects keyed by the local
+// names of their children, repeated children become arrays and elements
+// holding only text become strings.  Attributes other than namespace
+// declarations are kept as @name keys and the text of elements with children
+// or attributes as a #text key.
+func printJSON(w io.Writer, msg string) error {
+	root, err := parseXML(msg)
+	if err != nil {
+		return err
+	}
+
+	obj := &object{}
+	obj.add(root.name.Local, jsonValue(root))
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(obj)
+}
+
+func jsonValue(e *element) interface{} {
+	obj := &object{}
+	for _, a := range e.attrs {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+			continue
+		}
+		obj.add("@"+a.Name.Local, a.Value)
+	}
+
+	text := strings.TrimSpace(e.text)
+	if len(obj.keys) == 0 && len(e.children) == 0 {
+		return text
+	}
+	if text != "" {
+		obj.add("#text", text)
+	}
+
+	for _, child := range e.children {
+		name := child.name.Local
+		value := jsonValue(child)
+		i, ok := obj.index[name]
+		if !ok {
+			obj.add(name, value)
+			continue
+		}
+		if list, ok := obj.values[i].([]interface{}); ok {
+			obj.values[i] = append(list, value)
+		} else {
+			obj.values[i] = []interface{}{obj.values[i], value}
+		}
+	}
+	return obj
+}
+
+// object is a JSON object keeping the order of its keys
+type object struct {
+	keys   []string
+	values []interface{}
+	index  map[string]int
+}
+
+func (o *object) add(key string, value interface{}) {
+	if o.index == nil {
+		o.index = make(map[string]int)
+	}
+	o.index[key] = len(o.keys)
+	o.keys = append(o.keys, key)
+	o.values = append(o.values, value)
+}
+
+// MarshalJSON implements json.Marshaler
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	// Encode appends a newline, which is valid JSON whitespace
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := enc.Encode(key); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		if err := enc.Encode(o.values[i]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,87 @@
+// Go NETCONF Client - Command Line Tool
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/" message-id="1">
+<data><interfaces><interface><name>ge-0/0/0</name><mtu>1500</mtu><description>a &amp; b</description></interface>
+<interface junos:changed="true"><name>ge-0/0/1</name><disable/></interface></interfaces></data></rpc-reply>`
+
+func TestPrintXML(t *testing.T) {
+	expected := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/" message-id="1">
+  <data>
+    <interfaces>
+      <interface>
+        <name>ge-0/0/0</name>
+        <mtu>1500</mtu>
+        <description>a &amp; b</description>
+      </interface>
+      <interface junos:changed="true">
+        <name>ge-0/0/1</name>
+        <disable/>
+      </interface>
+    </interfaces>
+  </data>
+</rpc-reply>
+`
+
+	var buf bytes.Buffer
+	if err := printXML(&buf, testReply); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, buf.String()); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPrintJSON(t *testing.T) {
+	expected := `{
+  "rpc-reply": {
+    "@message-id": "1",
+    "data": {
+      "interfaces": {
+        "interface": [
+          {
+            "name": "ge-0/0/0",
+            "mtu": "1500",
+            "description": "a & b"
+          },
+          {
+            "@changed": "true",
+            "name": "ge-0/0/1",
+            "disable": ""
+          }
+        ]
+      }
+    }
+  }
+}
+`
+
+	var buf bytes.Buffer
+	if err := printJSON(&buf, testReply); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(expected, buf.String()); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPrintInvalidXML(t *testing.T) {
+	for _, msg := range []string{"", "not xml", "<rpc-reply><data>"} {
+		var buf bytes.Buffer
+		if err := printXML(&buf, msg); err == nil {
+			t.Errorf("%q: expected error", msg)
+		}
+	}
+}
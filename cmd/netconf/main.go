@@ -0,0 +1,103 @@
+// Go NETCONF Client - Command Line Tool
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Command netconf connects to a NETCONF server and runs RPCs against it, in the
+manner of netconf-console.
+
+Usage:
+
+	netconf [flags] <command> [arguments]
+
+The commands are:
+
+	hello                         print the session-id and server capabilities
+	get [filter]                  get state and configuration data
+	get-config [source] [filter]  get the configuration of source, running by default
+	edit-config <target> <config> edit the configuration of target
+	lock [target]                 lock target, candidate by default
+	unlock [target]               unlock target, candidate by default
+	commit                        commit the candidate configuration
+	raw <rpc>                     send the rpc as-is
+	repl                          run commands interactively
+	backup <inventory> <dir>      back up the configuration of many devices
+
+Filters, configurations and rpcs are given inline, read from a file with
+@file or from standard input with -.  Replies are printed as indented XML, or
+JSON with -format json.
+
+The backup command retrieves the configuration of the devices of an inventory
+file, one device name per line optionally followed by its address, and writes
+it to one file per device and format (-formats xml,text,set).  Timestamps and
+comments are removed so that only configuration changes show up, and the
+files can be committed to a git working tree with -git.  The devices whose
+configuration changed are reported.
+
+The server is reached over SSH by default, TLS with -transport tls or the
+local Junos shell with -transport junos.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: netconf [flags] <command> [arguments]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-30s %s\n", c.usage, c.help)
+	}
+	fmt.Fprintf(os.Stderr, "\nflags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	var opts options
+	opts.register(flag.CommandLine)
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	name, args := flag.Arg(0), flag.Args()[1:]
+	if name == "backup" {
+		if err := runBackup(&opts, args, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "netconf: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cmd := lookupCommand(name)
+	if cmd == nil && name != "repl" {
+		fmt.Fprintf(os.Stderr, "netconf: unknown command %q\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	s, err := opts.dial()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "netconf: %v\n", err)
+		os.Exit(1)
+	}
+	defer s.Close()
+
+	c := &client{session: s, out: os.Stdout, in: os.Stdin, format: opts.format}
+	if name == "repl" {
+		err = c.repl(os.Stdin, os.Stdout)
+	} else {
+		err = c.run(cmd, args)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "netconf: %v\n", err)
+		s.Close()
+		os.Exit(1)
+	}
+}
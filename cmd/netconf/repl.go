@@ -0,0 +1,137 @@
+// Go NETCONF Client - Command Line Tool
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const replPrompt = "netconf> "
+
+// repl runs the commands read from in until quit or the end of input.  On a
+// terminal, lines are edited with history recalled by the arrow keys.
+func (c *client) repl(in *os.File, out io.Writer) error {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		r := bufio.NewReader(in)
+		return c.replLoop(func() (string, error) {
+			line, err := r.ReadString('\n')
+			if err == io.EOF && line != "" {
+				err = nil
+			}
+			return line, err
+		})
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{in, out}, replPrompt)
+	if width, height, err := term.GetSize(fd); err == nil {
+		t.SetSize(width, height)
+	}
+	// The terminal translates newlines while in raw mode
+	c.out = t
+	return c.replLoop(t.ReadLine)
+}
+
+// replLoop runs the lines returned by readLine until quit or io.EOF.
+// Command errors are printed and do not end the loop.
+func (c *client) replLoop(readLine func() (string, error)) error {
+	var history []string
+	for {
+		line, err := readLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		// !n runs the nth line of the history again
+		if strings.HasPrefix(line, "!") {
+			n, err := strconv.Atoi(line[1:])
+			if err != nil || n < 1 || n > len(history) {
+				fmt.Fprintf(c.out, "error: no history entry %s\n", line[1:])
+				continue
+			}
+			line = history[n-1]
+			fmt.Fprintln(c.out, line)
+		}
+		history = append(history, line)
+
+		name := line
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			name = line[:i]
+		}
+		switch name {
+		case "quit", "exit":
+			return nil
+		case "help":
+			for _, cmd := range commands {
+				if cmd.run != nil {
+					fmt.Fprintf(c.out, "%-30s %s\n", cmd.usage, cmd.help)
+				}
+			}
+			fmt.Fprintf(c.out, "%-30s %s\n", "history", "list the lines entered")
+			fmt.Fprintf(c.out, "%-30s %s\n", "!n", "run the nth line again")
+			fmt.Fprintf(c.out, "%-30s %s\n", "quit", "end the session")
+			continue
+		case "history":
+			for i, h := range history {
+				fmt.Fprintf(c.out, "%5d  %s\n", i+1, h)
+			}
+			continue
+		}
+
+		cmd := lookupCommand(name)
+		if cmd == nil {
+			fmt.Fprintf(c.out, "error: unknown command %q, try help\n", name)
+			continue
+		}
+		args := splitArgs(strings.TrimSpace(line[len(name):]), cmd.maxArgs)
+		if err := c.run(cmd, args); err != nil {
+			fmt.Fprintf(c.out, "error: %v\n", err)
+		}
+	}
+}
+
+// splitArgs splits s on whitespace into at most n arguments, the last one
+// holding the rest of s.  XML data, starting with <, is never split.
+func splitArgs(s string, n int) []string {
+	var args []string
+	for s != "" {
+		if len(args) == n-1 || strings.HasPrefix(s, "<") {
+			return append(args, s)
+		}
+		i := strings.IndexAny(s, " \t")
+		if i < 0 {
+			return append(args, s)
+		}
+		args = append(args, s[:i])
+		s = strings.TrimSpace(s[i:])
+	}
+	return args
+}
@@ -8,7 +8,6 @@ package main
 
 import (
 	"bufio"
-	"encoding/xml"
 	"flag"
 	"fmt"
 	"log"
@@ -82,7 +81,10 @@ func BuildConfig() *ssh.ClientConfig {
 		}
 		fmt.Println()
 
-		config = netconf.SSHConfigPassword(*username, string(bytePassword))
+		config = &ssh.ClientConfig{
+			User: *username,
+			Auth: []ssh.AuthMethod{ssh.Password(string(bytePassword))},
+		}
 	}
 	return config
 }
@@ -97,7 +99,11 @@ func main() {
 		*username = r.Text()
 	}
 
-	config := BuildConfig()
+	// Verify the device against ~/.ssh/known_hosts
+	config, err := netconf.SSHConfigKnownHosts(BuildConfig())
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	s, err := netconf.DialSSH(*host, config)
 	if err != nil {
@@ -111,7 +117,7 @@ func main() {
 	}
 	var q SystemInformation
 
-	err = xml.Unmarshal([]byte(reply.RawReply), &q)
+	err = reply.Unmarshal(&q)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -6,4 +6,5 @@ go 1.12
 require (
 	github.com/google/go-cmp v0.5.1
 	golang.org/x/crypto v0.17.0
+	golang.org/x/term v0.15.0
 )
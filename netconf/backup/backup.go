@@ -0,0 +1,290 @@
+// Go NETCONF Client - Configuration backup
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package backup retrieves the configuration of many devices, normalizes it and
+writes it to per-device files, optionally committed to a git working tree,
+reporting the devices whose configuration changed.
+*/
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Juniper/go-netconf/netconf"
+	"golang.org/x/crypto/ssh"
+)
+
+// Formats the configuration is retrieved in.
+const (
+	// FormatXML retrieves the running configuration with get-config
+	FormatXML = "xml"
+	// FormatText retrieves the Junos configuration in text format
+	FormatText = netconf.JunosFormatText
+	// FormatSet retrieves the Junos configuration as set commands
+	FormatSet = netconf.JunosFormatSet
+)
+
+// extensions are the file extensions of each format
+var extensions = map[string]string{
+	FormatXML:  ".xml",
+	FormatText: ".conf",
+	FormatSet:  ".set",
+}
+
+// Backup writes the configuration of devices to Dir, in files named after
+// the device and the format: <name>.xml, <name>.conf for the text format and
+// <name>.set.  Names are escaped like URL path segments, e.g. a/b is written
+// to a%2Fb.xml, so distinct names never share a file.
+type Backup struct {
+	// Dir is the directory the files are written to
+	Dir string
+	// Formats are the formats retrieved, xml only by default
+	Formats []string
+	// Git commits the files written when set, Dir being in a git working
+	// tree
+	Git bool
+	// Message is the git commit message, "Configuration backup" by default
+	Message string
+	// Concurrency and Timeout configure the netconf.FanOut retrieving the
+	// configurations
+	Concurrency int
+	Timeout     time.Duration
+
+	dial func(ctx context.Context, target string) (*netconf.Session, error)
+}
+
+// Result is the outcome of the backup of a device.
+type Result struct {
+	Device Device
+	// Files are the files written, changed or not
+	Files []string
+	// Changed is set when a file was created or modified
+	Changed bool
+	// Err is the error retrieving or writing the configuration, the files
+	// of the device being left as they were
+	Err error
+}
+
+// Report is the outcome of a backup.
+type Report struct {
+	// Results are the results of every device, in inventory order
+	Results []Result
+	// Commit is the hash of the git commit, empty when nothing was
+	// committed
+	Commit string
+}
+
+// Changed returns the names of the devices whose configuration changed.
+func (r *Report) Changed() []string {
+	var names []string
+	for _, res := range r.Results {
+		if res.Changed {
+			names = append(names, res.Device.Name)
+		}
+	}
+	return names
+}
+
+// Failed returns the results of the devices that failed.
+func (r *Report) Failed() []Result {
+	var failed []Result
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// New creates a Backup to dir opening sessions with dial.
+func New(dir string, dial func(ctx context.Context, target string) (*netconf.Session, error)) *Backup {
+	return &Backup{Dir: dir, dial: dial}
+}
+
+// NewSSH creates a Backup to dir opening SSH sessions.  See
+// netconf.TransportSSH.Dial for config.
+func NewSSH(dir string, config *ssh.ClientConfig) *Backup {
+	return New(dir, func(ctx context.Context, target string) (*netconf.Session, error) {
+		return netconf.DialSSHContext(ctx, target, config)
+	})
+}
+
+// Run backs up the devices.  Device failures are reported in the results,
+// the error being returned when the backup as a whole failed, e.g. when the
+// git commit failed.
+func (b *Backup) Run(ctx context.Context, devices []Device) (*Report, error) {
+	formats := b.Formats
+	if len(formats) == 0 {
+		formats = []string{FormatXML}
+	}
+	for _, format := range formats {
+		if _, ok := extensions[format]; !ok {
+			return nil, fmt.Errorf("backup: unknown format %q", format)
+		}
+	}
+
+	byTarget := make(map[string]int)
+	targets := make([]string, len(devices))
+	for i, d := range devices {
+		if _, ok := byTarget[d.Target]; ok {
+			return nil, fmt.Errorf("backup: duplicate target %s", d.Target)
+		}
+		byTarget[d.Target] = i
+		targets[i] = d.Target
+	}
+
+	if err := os.MkdirAll(b.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	configs := make(map[string]map[string]string)
+
+	fanout := netconf.NewFanOut(b.dial)
+	fanout.Concurrency = b.Concurrency
+	fanout.Timeout = b.Timeout
+
+	job := fanout.Run(ctx, targets, func(ctx context.Context, target string, s *netconf.Session) (*netconf.RPCReply, error) {
+		device := make(map[string]string)
+		for _, format := range formats {
+			config, err := getConfig(ctx, s, format)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", format, err)
+			}
+			device[format] = Normalize(format, config)
+		}
+
+		mu.Lock()
+		configs[target] = device
+		mu.Unlock()
+		return nil, nil
+	})
+
+	report := &Report{Results: make([]Result, len(devices))}
+	for i, d := range devices {
+		report.Results[i].Device = d
+	}
+	for res := range job.Results() {
+		r := &report.Results[byTarget[res.Target]]
+		if res.Err != nil {
+			r.Err = res.Err
+			continue
+		}
+		mu.Lock()
+		device := configs[res.Target]
+		mu.Unlock()
+		r.Files, r.Changed, r.Err = b.write(r.Device, formats, device)
+	}
+
+	if !b.Git {
+		return report, nil
+	}
+
+	// Unchanged files are committed too, in case a previous commit failed.
+	// git runs in Dir, so files are given relative to it.
+	var files []string
+	for _, r := range report.Results {
+		for _, file := range r.Files {
+			files = append(files, filepath.Base(file))
+		}
+	}
+	if len(files) == 0 {
+		return report, nil
+	}
+	message := b.Message
+	if message == "" {
+		message = "Configuration backup"
+	}
+	if changed := report.Changed(); len(changed) > 0 {
+		message += "\n\nChanged: " + strings.Join(changed, ", ") + "\n"
+	}
+
+	commit, err := gitCommit(b.Dir, files, message)
+	if err != nil {
+		return report, err
+	}
+	report.Commit = commit
+	return report, nil
+}
+
+// getConfig retrieves the configuration of s in format
+func getConfig(ctx context.Context, s *netconf.Session, format string) (string, error) {
+	if format != FormatXML {
+		return s.GetConfiguration(ctx, format)
+	}
+
+	var data struct {
+		Inner string `xml:",innerxml"`
+	}
+	err := s.GetConfig(ctx, "running", "", &data)
+	return data.Inner, err
+}
+
+// write writes the configurations of d, reporting whether a file changed
+func (b *Backup) write(d Device, formats []string, configs map[string]string) ([]string, bool, error) {
+	var files []string
+	changed := false
+	for _, format := range formats {
+		file := filepath.Join(b.Dir, fileName(d.Name)+extensions[format])
+		content := []byte(configs[format])
+
+		old, err := ioutil.ReadFile(file)
+		if err == nil && bytes.Equal(old, content) {
+			files = append(files, file)
+			continue
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return files, changed, err
+		}
+
+		if err := writeFile(file, content); err != nil {
+			return files, changed, err
+		}
+		files = append(files, file)
+		changed = true
+	}
+	return files, changed, nil
+}
+
+// writeFile replaces file with content through a temporary file, so that a
+// failure never leaves a truncated backup
+func writeFile(file string, content []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".backup")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// fileName returns a file name for a device name, which may contain path
+// separators.  Escaping keeps distinct names apart, unlike replacing the
+// separators.
+func fileName(name string) string {
+	return url.PathEscape(name)
+}
@@ -0,0 +1,207 @@
+// Go NETCONF Client - Configuration backup
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Juniper/go-netconf/netconf"
+	"github.com/Juniper/go-netconf/netconf/netconftest"
+	"github.com/google/go-cmp/cmp"
+)
+
+// backupTest dials fake servers answering with the configuration of their
+// device, set in hostNames
+type backupTest struct {
+	mu        sync.Mutex
+	hostNames map[string]string
+	servers   []*netconftest.Server
+}
+
+func (bt *backupTest) dial(ctx context.Context, target string) (*netconf.Session, error) {
+	bt.mu.Lock()
+	hostName, ok := bt.hostNames[target]
+	bt.mu.Unlock()
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+
+	server := netconftest.NewServer()
+	server.Handle(netconftest.MatchName("get-config"), netconftest.ReplyData(fmt.Sprintf(
+		`<configuration junos:changed-seconds="%d"><system><host-name>%s</host-name></system></configuration>`, len(bt.servers), hostName)))
+	server.Handle(netconftest.MatchXPath("get-configuration[@format='text']"), netconftest.Reply(fmt.Sprintf(
+		"<configuration-text>\n## Last commit: %d\nsystem {\n    host-name %s;\n}\n</configuration-text>", len(bt.servers), hostName)))
+
+	bt.mu.Lock()
+	bt.servers = append(bt.servers, server)
+	bt.mu.Unlock()
+	return server.Session(), nil
+}
+
+func (bt *backupTest) close() {
+	for _, s := range bt.servers {
+		s.Close()
+	}
+}
+
+func TestBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bt := &backupTest{hostNames: map[string]string{"10.0.0.1": "r1", "10.0.0.2": "r2"}}
+	defer bt.close()
+
+	devices := []Device{
+		{Name: "r1", Target: "10.0.0.1"},
+		{Name: "r2", Target: "10.0.0.2"},
+		{Name: "r3", Target: "10.0.0.3"},
+	}
+	b := New(dir, bt.dial)
+	b.Formats = []string{FormatXML, FormatText}
+
+	report, err := b.Run(context.Background(), devices)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"r1", "r2"}, report.Changed()); diff != "" {
+		t.Errorf("changed mismatch (-want +got):\n%s", diff)
+	}
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].Device.Name != "r3" || failed[0].Err.Error() != "connection refused" {
+		t.Errorf("unexpected failures %+v", failed)
+	}
+
+	files := map[string]string{
+		"r1.xml":  "<configuration><system><host-name>r1</host-name></system></configuration>\n",
+		"r1.conf": "system {\n    host-name r1;\n}\n",
+		"r2.xml":  "<configuration><system><host-name>r2</host-name></system></configuration>\n",
+		"r2.conf": "system {\n    host-name r2;\n}\n",
+	}
+	for name, expected := range files {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		} else if string(b) != expected {
+			t.Errorf("%s: got %q, expected %q", name, b, expected)
+		}
+	}
+
+	// The commit timestamps differ between runs but are normalized away
+	report, err = b.Run(context.Background(), devices)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed := report.Changed(); len(changed) != 0 {
+		t.Errorf("got changed %v, expected none", changed)
+	}
+
+	bt.hostNames["10.0.0.2"] = "r2-new"
+	report, err = b.Run(context.Background(), devices)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"r2"}, report.Changed()); diff != "" {
+		t.Errorf("changed mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBackupGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "backup"},
+		{"config", "user.email", "backup@example.net"},
+	} {
+		if _, err := git(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bt := &backupTest{hostNames: map[string]string{"10.0.0.1": "r1"}}
+	defer bt.close()
+
+	devices := []Device{{Name: "r1", Target: "10.0.0.1"}}
+	b := New(dir, bt.dial)
+	b.Git = true
+	b.Message = "Nightly backup"
+
+	report, err := b.Run(context.Background(), devices)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Commit == "" {
+		t.Fatalf("nothing committed")
+	}
+	message, err := git(dir, "log", "-1", "--format=%B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message != "Nightly backup\n\nChanged: r1" {
+		t.Errorf("unexpected commit message %q", message)
+	}
+
+	report, err = b.Run(context.Background(), devices)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Commit != "" {
+		t.Errorf("unexpected commit %s without changes", report.Commit)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tt := []struct {
+		name     string
+		expected string
+	}{
+		{"r1", "r1"},
+		{"a/b", "a%2Fb"},
+		{"a_b", "a_b"},
+		{`a\b`, "a%5Cb"},
+		{"a%2Fb", "a%252Fb"},
+	}
+
+	for _, tc := range tt {
+		if got := fileName(tc.name); got != tc.expected {
+			t.Errorf("fileName(%q) = %q, expected %q", tc.name, got, tc.expected)
+		}
+	}
+}
+
+func TestBackupErrors(t *testing.T) {
+	b := New("unused", nil)
+	b.Formats = []string{"json"}
+	if _, err := b.Run(context.Background(), nil); err == nil || err.Error() != `backup: unknown format "json"` {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	b.Formats = nil
+	devices := []Device{{Name: "a", Target: "r1"}, {Name: "b", Target: "r1"}}
+	if _, err := b.Run(context.Background(), devices); err == nil || err.Error() != "backup: duplicate target r1" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+// Go NETCONF Client - Configuration backup
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// git runs the git command with args in dir, returning its output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("backup: git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// gitCommit commits files in the working tree of dir, returning the hash of
+// the commit, or an empty hash when the files were already committed as-is
+func gitCommit(dir string, files []string, message string) (string, error) {
+	if _, err := git(dir, append([]string{"add", "--"}, files...)...); err != nil {
+		return "", err
+	}
+
+	// diff --quiet fails when files are staged
+	if _, err := git(dir, append([]string{"diff", "--cached", "--quiet", "--"}, files...)...); err == nil {
+		return "", nil
+	}
+
+	if _, err := git(dir, append([]string{"commit", "-q", "-m", message, "--"}, files...)...); err != nil {
+		return "", err
+	}
+	return git(dir, "rev-parse", "HEAD")
+}
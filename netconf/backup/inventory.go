@@ -0,0 +1,69 @@
+// Go NETCONF Client - Configuration backup
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Device is a device whose configuration is backed up.
+type Device struct {
+	// Name names the files of the device
+	Name string
+	// Target is the address dialed, see netconf.TransportSSH.Dial
+	Target string
+}
+
+// ParseInventory reads devices, one per line as a name optionally followed
+// by the target dialed, the name itself by default.  Blank lines and lines
+// starting with # are ignored.
+//
+//	# core routers
+//	r1 10.0.0.1
+//	r2.example.net
+func ParseInventory(r io.Reader) ([]Device, error) {
+	var devices []Device
+	names := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("backup: inventory line %d: expected a name and an optional target", n)
+		}
+		d := Device{Name: fields[0], Target: fields[0]}
+		if len(fields) == 2 {
+			d.Target = fields[1]
+		}
+		if names[d.Name] {
+			return nil, fmt.Errorf("backup: inventory line %d: duplicate device %s", n, d.Name)
+		}
+		names[d.Name] = true
+		devices = append(devices, d)
+	}
+	return devices, scanner.Err()
+}
+
+// LoadInventory reads the devices of an inventory file, see ParseInventory.
+func LoadInventory(file string) ([]Device, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseInventory(f)
+}
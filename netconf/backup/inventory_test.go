@@ -0,0 +1,60 @@
+// Go NETCONF Client - Configuration backup
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseInventory(t *testing.T) {
+	tt := []struct {
+		name     string
+		input    string
+		expected []Device
+		err      string
+	}{
+		{
+			name:  "valid",
+			input: "# core routers\nr1 10.0.0.1:830\n\n  r2.example.net  \n",
+			expected: []Device{
+				{Name: "r1", Target: "10.0.0.1:830"},
+				{Name: "r2.example.net", Target: "r2.example.net"},
+			},
+		},
+		{
+			name:  "extraFields",
+			input: "r1 10.0.0.1 extra\n",
+			err:   "backup: inventory line 1: expected a name and an optional target",
+		},
+		{
+			name:  "duplicate",
+			input: "r1 10.0.0.1\n# r1\nr1 10.0.0.2\n",
+			err:   "backup: inventory line 3: duplicate device r1",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			devices, err := ParseInventory(strings.NewReader(tc.input))
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("got error %v, expected %s", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, devices); diff != "" {
+				t.Errorf("devices mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Go NETCONF Client - Configuration backup
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// xmlComment matches XML comments
+	xmlComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+	// xmlCommitAttr matches the Junos attributes recording when and by whom
+	// the configuration was changed
+	xmlCommitAttr = regexp.MustCompile(`\s+junos:(changed|commit)-[a-z]+="[^"]*"`)
+	// trailingAnnotation matches the ## SECRET-DATA and ## Last changed
+	// annotations Junos appends to statements, outside of quoted values
+	trailingAnnotation = regexp.MustCompile(`^((?:[^"]|"(?:[^"\\]|\\.)*")*?)\s+## (?:SECRET-DATA|Last changed).*$`)
+)
+
+// Normalize removes what changes between two retrievals of the same
+// configuration in the given format, so that only configuration changes show
+// up in the backups.  Comments are removed: XML comments and the Junos
+// commit attributes for the xml format, and for the text and set formats the
+// ## comment lines and the ## SECRET-DATA and ## Last changed annotations
+// ending statements.  Quoted values are left untouched.  Trailing
+// whitespace and blank lines at the start and end are removed too.
+func Normalize(format string, config string) string {
+	if format == FormatXML {
+		config = xmlComment.ReplaceAllString(config, "")
+		config = xmlCommitAttr.ReplaceAllString(config, "")
+	}
+
+	var lines []string
+	for _, line := range strings.Split(config, "\n") {
+		if format == FormatText || format == FormatSet {
+			if strings.HasPrefix(strings.TrimSpace(line), "#") {
+				continue
+			}
+			line = trailingAnnotation.ReplaceAllString(line, "$1")
+		}
+		line = strings.TrimRight(line, " \t\r")
+		// Lines emptied by the removal of comments are dropped
+		if line == "" && (len(lines) == 0 || format == FormatXML) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,85 @@
+// Go NETCONF Client - Configuration backup
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tt := []struct {
+		name     string
+		format   string
+		config   string
+		expected string
+	}{
+		{
+			name:   "xml",
+			format: FormatXML,
+			config: `
+<!-- user admin last modified -->
+<configuration junos:changed-seconds="1514764800" junos:changed-localtime="2018-01-01 00:00:00 UTC">
+    <version>17.3R1</version>
+    <!-- comment
+         on several lines -->
+    <system><host-name>r1</host-name></system>
+</configuration>
+`,
+			expected: "<configuration>\n    <version>17.3R1</version>\n    <system><host-name>r1</host-name></system>\n</configuration>\n",
+		},
+		{
+			name:   "text",
+			format: FormatText,
+			config: `
+## Last commit: 2018-01-01 00:00:00 UTC by admin
+version 17.3R1;
+system {
+    host-name r1;
+
+    root-authentication {
+        encrypted-password "$6$abc"; ## SECRET-DATA
+    }
+}
+
+`,
+			expected: "version 17.3R1;\nsystem {\n    host-name r1;\n\n    root-authentication {\n        encrypted-password \"$6$abc\";\n    }\n}\n",
+		},
+		{
+			name:     "set",
+			format:   FormatSet,
+			config:   "\n## Last changed: 2018-01-01 00:00:00 UTC\nset version 17.3R1\nset system host-name r1 \n",
+			expected: "set version 17.3R1\nset system host-name r1\n",
+		},
+		{
+			name:     "quoted",
+			format:   FormatText,
+			config:   "interfaces {\n    ge-0/0/0 {\n        description \"a ## b\"; ## Last changed: 2018-01-01 00:00:00 UTC\n        unit 0 { description \"c \\\" ## SECRET-DATA\"; }\n    }\n}\n",
+			expected: "interfaces {\n    ge-0/0/0 {\n        description \"a ## b\";\n        unit 0 { description \"c \\\" ## SECRET-DATA\"; }\n    }\n}\n",
+		},
+		{
+			name:     "setQuoted",
+			format:   FormatSet,
+			config:   "set system login message \"a ## b\"\nset system root-authentication encrypted-password \"$6$abc\" ## SECRET-DATA\n",
+			expected: "set system login message \"a ## b\"\nset system root-authentication encrypted-password \"$6$abc\"\n",
+		},
+		{
+			name:     "empty",
+			format:   FormatText,
+			config:   "\n## Last changed: 2018-01-01 00:00:00 UTC\n\n",
+			expected: "",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Normalize(tc.format, tc.config)
+			if got != tc.expected {
+				t.Errorf("got %q, expected %q", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+// Go NETCONF Client - Configuration diff
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package configdiff compares two configurations semantically and computes the
+edit-config payload transforming one into the other, for instance the minimal
+changes from the running configuration fetched with MethodGetConfig to an
+intended configuration:
+
+	running, _ := configdiff.Parse([]byte(reply.Data))
+	intended, _ := configdiff.Parse(intendedXML)
+	opts := &configdiff.Options{Keys: map[string][]string{"interface": {"name"}}}
+	for _, c := range configdiff.Diff(running, intended, opts) {
+		fmt.Println(c)
+	}
+	s.Exec(netconf.MethodEditConfig("candidate", configdiff.EditConfig(running, intended, opts)))
+
+Elements are compared by namespace and local name, whitespace around text is
+ignored and the entries of the lists whose keys are known are matched by key
+regardless of their order.  Repeated leaves are matched by value as leaf-list
+entries, and other repeated elements by position.
+*/
+package configdiff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operations of a Change.
+const (
+	OpCreate = "create"
+	OpDelete = "delete"
+	OpModify = "modify"
+)
+
+// Edit-config operations of the payload returned by EditConfig.
+const (
+	OperationMerge   = "merge"
+	OperationDelete  = "delete"
+	OperationReplace = "replace"
+)
+
+// Options configure how elements are matched.
+type Options struct {
+	// Keys maps the element names of YANG lists to the names of their key
+	// leaves, e.g. "interface": {"name"}.  Names are local names, or
+	// "{namespace}local" to restrict them to a namespace.
+	Keys map[string][]string
+	// LeafLists are the element names of YANG leaf-lists, matched by value
+	// even when they have a single entry.
+	LeafLists []string
+}
+
+// Change is a difference between two configurations.
+type Change struct {
+	// Op is OpCreate, OpDelete or OpModify
+	Op string
+	// Path locates the element, e.g. /interfaces/interface[name='ge-0/0/0']/mtu
+	Path string
+	// Old and New are the element in each configuration, Old is nil for
+	// created elements and New for deleted ones
+	Old *Node
+	New *Node
+}
+
+// String returns the change in the manner of a unified diff.
+func (c Change) String() string {
+	switch c.Op {
+	case OpCreate:
+		return "+ " + c.Path + value(c.New)
+	case OpDelete:
+		return "- " + c.Path + value(c.Old)
+	}
+	if c.Old.isLeaf() && c.New.isLeaf() {
+		return fmt.Sprintf("~ %s: %q -> %q", c.Path, c.Old.Text, c.New.Text)
+	}
+	return "~ " + c.Path
+}
+
+func value(n *Node) string {
+	if n.isLeaf() && n.Text != "" {
+		return fmt.Sprintf(" = %q", n.Text)
+	}
+	return ""
+}
+
+// Diff returns the changes turning the configuration a into b.  <config> and
+// <data> root elements of the NETCONF base namespace, or without namespace as
+// parsed from RPCReply.Data, are ignored.
+func Diff(a, b *Node, opts *Options) []Change {
+	d := &differ{opts: opts}
+	d.diff("", roots(a), roots(b))
+	return d.changes
+}
+
+// EditConfig returns the edit-config <config> contents turning the
+// configuration a into b, for MethodEditConfig with the merge default
+// operation.  Created elements and changed leaves are merged, deleted
+// elements deleted, and changed elements matched by position replaced.  It
+// is empty when the configurations are equivalent.
+func EditConfig(a, b *Node, opts *Options) string {
+	d := &differ{opts: opts}
+	edits := d.diff("", roots(a), roots(b))
+	if len(edits) == 0 {
+		return ""
+	}
+
+	// The elements are written in the base namespace of the <config> of
+	// MethodEditConfig, each declaring the nc prefix of the operations
+	w := &xmlWriter{declareNC: true}
+	for _, n := range edits {
+		w.write(n, baseNS)
+	}
+	return w.String()
+}
+
+func roots(n *Node) []*Node {
+	if n == nil {
+		return nil
+	}
+	// RPCReply.Data holds the inner XML of the reply, <data> losing the base
+	// namespace it inherited from <rpc-reply>
+	if (n.Name.Space == baseNS || n.Name.Space == "") && (n.Name.Local == "config" || n.Name.Local == "data") {
+		return n.Children
+	}
+	return []*Node{n}
+}
+
+type differ struct {
+	opts    *Options
+	changes []Change
+}
+
+// pair is a matched element, either side is nil when it only exists in the
+// other configuration
+type pair struct {
+	a, b *Node
+	// path is the path of the element
+	path string
+	// positional is set when the element is only identified by its position
+	positional bool
+}
+
+// diff records the changes between the children as and bs of the element at
+// path and returns the edits turning them into bs.
+func (d *differ) diff(path string, as, bs []*Node) []*Node {
+	var edits []*Node
+	for _, p := range d.match(path, as, bs) {
+		switch {
+		case p.a == nil:
+			d.changes = append(d.changes, Change{Op: OpCreate, Path: p.path, New: p.b})
+			edits = append(edits, withOp(p.b.copy(), OperationMerge))
+		case p.b == nil:
+			d.changes = append(d.changes, Change{Op: OpDelete, Path: p.path, Old: p.a})
+			edits = append(edits, d.deleted(p))
+		case p.a.isLeaf() && p.b.isLeaf():
+			if p.a.Text != p.b.Text || !attrsEqual(p.a.Attrs, p.b.Attrs) {
+				d.changes = append(d.changes, Change{Op: OpModify, Path: p.path, Old: p.a, New: p.b})
+				edits = append(edits, withOp(p.b.copy(), OperationMerge))
+			}
+		case p.positional || p.a.isLeaf() || p.b.isLeaf() || !attrsEqual(p.a.Attrs, p.b.Attrs):
+			// The element cannot be addressed or edited in place
+			sub := &differ{opts: d.opts}
+			sub.diff(p.path, p.a.Children, p.b.Children)
+			if len(sub.changes) > 0 || p.a.isLeaf() != p.b.isLeaf() || !attrsEqual(p.a.Attrs, p.b.Attrs) {
+				d.changes = append(d.changes, Change{Op: OpModify, Path: p.path, Old: p.a, New: p.b})
+				edits = append(edits, withOp(p.b.copy(), OperationReplace))
+			}
+		default:
+			children := d.diff(p.path, p.a.Children, p.b.Children)
+			if len(children) > 0 {
+				n := &Node{Name: p.b.Name, Attrs: p.b.Attrs}
+				n.Children = append(d.keyLeaves(p.b), children...)
+				edits = append(edits, n)
+			}
+		}
+	}
+	return edits
+}
+
+// deleted returns the edit deleting the element of p, with its key leaves
+// or value to identify it.  Elements matched by position are identified by
+// their whole contents.
+func (d *differ) deleted(p pair) *Node {
+	if p.positional {
+		return withOp(p.a.copy(), OperationDelete)
+	}
+	n := &Node{Name: p.a.Name, Attrs: p.a.Attrs, Text: p.a.Text, Children: d.keyLeaves(p.a)}
+	return withOp(n, OperationDelete)
+}
+
+func withOp(n *Node, op string) *Node {
+	n.op = op
+	return n
+}
+
+// keys returns the key leaf names of the list element named local in space.
+func (d *differ) keys(space, local string) []string {
+	if d.opts == nil {
+		return nil
+	}
+	if keys, ok := d.opts.Keys["{"+space+"}"+local]; ok {
+		return keys
+	}
+	return d.opts.Keys[local]
+}
+
+func (d *differ) isLeafList(space, local string) bool {
+	if d.opts == nil {
+		return false
+	}
+	for _, name := range d.opts.LeafLists {
+		if name == local || name == "{"+space+"}"+local {
+			return true
+		}
+	}
+	return false
+}
+
+// keyLeaves returns copies of the key leaves of n, nil unless n is an entry
+// of a list with known keys.
+func (d *differ) keyLeaves(n *Node) []*Node {
+	var leaves []*Node
+	for _, k := range d.keys(n.Name.Space, n.Name.Local) {
+		leaf := n.child(k)
+		if leaf == nil {
+			return nil
+		}
+		leaves = append(leaves, leaf.copy())
+	}
+	return leaves
+}
+
+// match pairs the elements of as and bs, in the order of as followed by the
+// elements only in bs.
+func (d *differ) match(path string, as, bs []*Node) []pair {
+	counts := map[string][2]int{}
+	leaves := map[string]bool{}
+	for i, nodes := range [][]*Node{as, bs} {
+		for _, n := range nodes {
+			name := "{" + n.Name.Space + "}" + n.Name.Local
+			c := counts[name]
+			c[i]++
+			counts[name] = c
+			if _, ok := leaves[name]; !ok {
+				leaves[name] = true
+			}
+			leaves[name] = leaves[name] && n.isLeaf()
+		}
+	}
+
+	ids := func(nodes []*Node) ([]string, []pair) {
+		ids := make([]string, len(nodes))
+		pairs := make([]pair, len(nodes))
+		seen := map[string]int{}
+		for i, n := range nodes {
+			name := "{" + n.Name.Space + "}" + n.Name.Local
+			c := counts[name]
+			segment, positional := n.Name.Local, false
+			keys := d.keyLeaves(n)
+			switch {
+			case keys != nil:
+				for _, leaf := range keys {
+					segment += fmt.Sprintf("[%s=%s]", leaf.Name.Local, quote(leaf.Text))
+				}
+			case leaves[name] && (c[0] > 1 || c[1] > 1 || d.isLeafList(n.Name.Space, n.Name.Local)):
+				segment += "[.=" + quote(n.Text) + "]"
+			case c[0] > 1 || c[1] > 1:
+				positional = true
+			}
+			id := "{" + n.Name.Space + "}" + segment
+			seen[id]++
+			if positional {
+				segment += "[" + strconv.Itoa(seen[id]) + "]"
+			}
+			ids[i] = id + "#" + strconv.Itoa(seen[id])
+			pairs[i] = pair{path: path + "/" + segment, positional: positional}
+		}
+		return ids, pairs
+	}
+	idsA, pairsA := ids(as)
+	idsB, pairsB := ids(bs)
+
+	indexB := make(map[string]int, len(bs))
+	for i, id := range idsB {
+		indexB[id] = i
+	}
+	matched := make([]bool, len(bs))
+	var pairs []pair
+	for i, id := range idsA {
+		p := pairsA[i]
+		p.a = as[i]
+		if j, ok := indexB[id]; ok {
+			p.b = bs[j]
+			matched[j] = true
+		}
+		pairs = append(pairs, p)
+	}
+	for j, b := range bs {
+		if !matched[j] {
+			p := pairsB[j]
+			p.b = b
+			pairs = append(pairs, p)
+		}
+	}
+	return pairs
+}
+
+// quote quotes s as an XPath literal.
+func quote(s string) string {
+	if strings.Contains(s, "'") {
+		return `"` + s + `"`
+	}
+	return "'" + s + "'"
+}
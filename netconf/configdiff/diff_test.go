@@ -0,0 +1,215 @@
+// Go NETCONF Client - Configuration diff
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package configdiff
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/Juniper/go-netconf/netconf"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testOptions = &Options{
+	Keys:      map[string][]string{"interface": {"name"}, "{urn:example:routing}route": {"prefix"}},
+	LeafLists: []string{"server"},
+}
+
+func TestDiff(t *testing.T) {
+	tt := []struct {
+		name     string
+		a, b     string
+		changes  []string
+		expected string
+	}{
+		{
+			name: "equivalent",
+			a: `<data xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+  <interfaces xmlns="urn:example:if">
+    <interface><name>ge-0/0/0</name><mtu>1500</mtu></interface>
+    <interface><name>ge-0/0/1</name><mtu>9000</mtu></interface>
+  </interfaces>
+</data>`,
+			b: `<config xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><if:interfaces xmlns:if="urn:example:if">` +
+				`<if:interface><if:mtu>9000</if:mtu><if:name>ge-0/0/1</if:name></if:interface>` +
+				`<if:interface><if:name> ge-0/0/0 </if:name><if:mtu>1500</if:mtu></if:interface>` +
+				`</if:interfaces></config>`,
+		},
+		{
+			name: "leaf",
+			a:    `<system xmlns="urn:example:system"><host-name>r1</host-name><location>lab</location></system>`,
+			b:    `<system xmlns="urn:example:system"><host-name>r2</host-name><contact>noc</contact></system>`,
+			changes: []string{
+				`~ /system/host-name: "r1" -> "r2"`,
+				`- /system/location = "lab"`,
+				`+ /system/contact = "noc"`,
+			},
+			expected: `<system xmlns="urn:example:system" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<host-name nc:operation="merge">r2</host-name>` +
+				`<location nc:operation="delete">lab</location>` +
+				`<contact nc:operation="merge">noc</contact></system>`,
+		},
+		{
+			name: "keyed list",
+			a: `<interfaces xmlns="urn:example:if">
+  <interface><name>ge-0/0/0</name><mtu>1500</mtu></interface>
+  <interface><name>ge-0/0/1</name><mtu>1500</mtu></interface>
+  <interface><name>ge-0/0/2</name><mtu>1500</mtu></interface>
+</interfaces>`,
+			b: `<interfaces xmlns="urn:example:if">
+  <interface><name>ge-0/0/3</name><mtu>1500</mtu></interface>
+  <interface><name>ge-0/0/2</name><mtu>1500</mtu></interface>
+  <interface><name>ge-0/0/0</name><mtu>9000</mtu></interface>
+</interfaces>`,
+			changes: []string{
+				`~ /interfaces/interface[name='ge-0/0/0']/mtu: "1500" -> "9000"`,
+				`- /interfaces/interface[name='ge-0/0/1']`,
+				`+ /interfaces/interface[name='ge-0/0/3']`,
+			},
+			expected: `<interfaces xmlns="urn:example:if" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<interface><name>ge-0/0/0</name><mtu nc:operation="merge">9000</mtu></interface>` +
+				`<interface nc:operation="delete"><name>ge-0/0/1</name></interface>` +
+				`<interface nc:operation="merge"><name>ge-0/0/3</name><mtu>1500</mtu></interface>` +
+				`</interfaces>`,
+		},
+		{
+			name: "leaf-list",
+			a:    `<ntp><server>10.0.0.1</server></ntp>`,
+			b:    `<ntp><server>10.0.0.2</server></ntp>`,
+			changes: []string{
+				`- /ntp/server[.='10.0.0.1'] = "10.0.0.1"`,
+				`+ /ntp/server[.='10.0.0.2'] = "10.0.0.2"`,
+			},
+			expected: `<ntp xmlns="" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<server nc:operation="delete">10.0.0.1</server>` +
+				`<server nc:operation="merge">10.0.0.2</server></ntp>`,
+		},
+		{
+			name: "repeated leaves",
+			a:    `<dns><search>a.example</search><search>b.example</search></dns>`,
+			b:    `<dns><search>b.example</search><search>a.example</search></dns>`,
+		},
+		{
+			name: "namespaces",
+			a: `<data xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<routes xmlns="urn:example:routing"><route><prefix>10.0.0.0/8</prefix><next-hop>192.0.2.1</next-hop></route></routes>` +
+				`<routes xmlns="urn:example:other"><route><prefix>10.0.0.0/8</prefix></route></routes></data>`,
+			b: `<data xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<routes xmlns="urn:example:routing"><route><prefix>10.0.0.0/8</prefix><next-hop>192.0.2.2</next-hop></route></routes>` +
+				`<routes xmlns="urn:example:other"><route><prefix>10.0.0.0/16</prefix></route></routes></data>`,
+			changes: []string{
+				`~ /routes/route[prefix='10.0.0.0/8']/next-hop: "192.0.2.1" -> "192.0.2.2"`,
+				`~ /routes/route/prefix: "10.0.0.0/8" -> "10.0.0.0/16"`,
+			},
+			expected: `<routes xmlns="urn:example:routing" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<route><prefix>10.0.0.0/8</prefix><next-hop nc:operation="merge">192.0.2.2</next-hop></route></routes>` +
+				`<routes xmlns="urn:example:other" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<route><prefix nc:operation="merge">10.0.0.0/16</prefix></route></routes>`,
+		},
+		{
+			name: "unkeyed list",
+			a:    `<users><user><login>a</login><class>ro</class></user><user><login>b</login><class>ro</class></user></users>`,
+			b:    `<users><user><login>a</login><class>ro</class></user><user><login>b</login><class>rw</class></user></users>`,
+			changes: []string{
+				`~ /users/user[2]`,
+			},
+			expected: `<users xmlns="" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<user nc:operation="replace"><login>b</login><class>rw</class></user></users>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := Parse([]byte(tc.a))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			b, err := Parse([]byte(tc.b))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var changes []string
+			for _, c := range Diff(a, b, testOptions) {
+				changes = append(changes, c.String())
+			}
+			if diff := cmp.Diff(tc.changes, changes); diff != "" {
+				t.Errorf("changes mismatch (-want +got):\n%s", diff)
+			}
+			if got := EditConfig(a, b, testOptions); got != tc.expected {
+				t.Errorf("got %s, expected %s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestChange(t *testing.T) {
+	a, err := Parse([]byte(`<system><host-name>r1</host-name><services><ssh/></services></system>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := Parse([]byte(`<system><host-name><name>r1</name></host-name></system>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changes := Diff(a, b, nil)
+	if len(changes) != 2 {
+		t.Fatalf("got %d changes, expected 2", len(changes))
+	}
+	if c := changes[0]; c.Op != OpModify || c.Old != a.Children[0] || c.New != b.Children[0] {
+		t.Errorf("unexpected change %+v", c)
+	}
+	if c := changes[1]; c.Op != OpDelete || c.Path != "/system/services" || c.Old != a.Children[1] || c.New != nil {
+		t.Errorf("unexpected change %+v", c)
+	}
+
+	expected := `<system xmlns="" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+		`<host-name nc:operation="replace"><name>r1</name></host-name>` +
+		`<services nc:operation="delete"/></system>`
+	if got := EditConfig(a, b, nil); got != expected {
+		t.Errorf("got %s, expected %s", got, expected)
+	}
+}
+
+func TestDiffRPCReplyData(t *testing.T) {
+	var reply netconf.RPCReply
+	err := xml.Unmarshal([]byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1">
+<data>
+  <configuration>
+    <system><host-name>r1</host-name></system>
+  </configuration>
+</data>
+</rpc-reply>`), &reply)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	running, err := Parse([]byte(reply.Data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	intended, err := Parse([]byte(`<configuration><system><host-name>r2</host-name></system></configuration>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var changes []string
+	for _, c := range Diff(running, intended, nil) {
+		changes = append(changes, c.String())
+	}
+	expected := []string{`~ /configuration/system/host-name: "r1" -> "r2"`}
+	if diff := cmp.Diff(expected, changes); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+
+	edit := `<configuration xmlns="" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+		`<system><host-name nc:operation="merge">r2</host-name></system></configuration>`
+	if got := EditConfig(running, intended, nil); got != edit {
+		t.Errorf("got %s, expected %s", got, edit)
+	}
+}
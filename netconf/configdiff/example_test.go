@@ -0,0 +1,45 @@
+// Go NETCONF Client - Configuration diff
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package configdiff_test
+
+import (
+	"fmt"
+
+	"github.com/Juniper/go-netconf/netconf/configdiff"
+)
+
+func Example() {
+	running, err := configdiff.Parse([]byte(`<configuration>
+  <system><host-name>r1</host-name></system>
+  <interfaces>
+    <interface><name>ge-0/0/0</name><mtu>1500</mtu></interface>
+    <interface><name>ge-0/0/1</name><mtu>1500</mtu></interface>
+  </interfaces>
+</configuration>`))
+	if err != nil {
+		panic(err)
+	}
+	intended, err := configdiff.Parse([]byte(`<configuration>
+  <system><host-name>r1</host-name></system>
+  <interfaces>
+    <interface><name>ge-0/0/1</name><mtu>1500</mtu></interface>
+    <interface><name>ge-0/0/0</name><mtu>9000</mtu></interface>
+  </interfaces>
+</configuration>`))
+	if err != nil {
+		panic(err)
+	}
+
+	opts := &configdiff.Options{Keys: map[string][]string{"interface": {"name"}}}
+	for _, c := range configdiff.Diff(running, intended, opts) {
+		fmt.Println(c)
+	}
+	fmt.Println(configdiff.EditConfig(running, intended, opts))
+	// Output:
+	// ~ /configuration/interfaces/interface[name='ge-0/0/0']/mtu: "1500" -> "9000"
+	// <configuration xmlns="" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0"><interfaces><interface><name>ge-0/0/0</name><mtu nc:operation="merge">9000</mtu></interface></interfaces></configuration>
+}
@@ -0,0 +1,214 @@
+// Go NETCONF Client - Configuration diff
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package configdiff
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// baseNS is the NETCONF base namespace, of the <config> and <data> wrappers
+// and the operation attribute.
+const baseNS = "urn:ietf:params:xml:ns:netconf:base:1.0"
+
+// Node is an element of a configuration.  Names carry the namespace URI
+// rather than the prefix used by the document.
+type Node struct {
+	Name     xml.Name
+	Attrs    []xml.Attr
+	Text     string
+	Children []*Node
+
+	// op is the edit-config operation of the node in a payload
+	op string
+}
+
+// Parse parses a configuration document.  Namespace declarations, comments,
+// processing instructions and whitespace around text are dropped, and the
+// text of elements with children is ignored.
+func Parse(data []byte) (*Node, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var root *Node
+	var stack []*Node
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("configdiff: %v", err)
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			n := &Node{Name: tok.Name}
+			for _, a := range tok.Attr {
+				if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+					continue
+				}
+				n.Attrs = append(n.Attrs, a)
+			}
+			sortAttrs(n.Attrs)
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, n)
+			} else if root == nil {
+				root = n
+			} else {
+				return nil, fmt.Errorf("configdiff: several root elements")
+			}
+			stack = append(stack, n)
+			text.Reset()
+		case xml.CharData:
+			if len(stack) > 0 {
+				text.Write(tok)
+			}
+		case xml.EndElement:
+			n := stack[len(stack)-1]
+			if len(n.Children) == 0 {
+				n.Text = strings.TrimSpace(text.String())
+			}
+			stack = stack[:len(stack)-1]
+			text.Reset()
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("configdiff: no root element")
+	}
+	return root, nil
+}
+
+func sortAttrs(attrs []xml.Attr) {
+	sort.Slice(attrs, func(i, j int) bool {
+		if attrs[i].Name.Space != attrs[j].Name.Space {
+			return attrs[i].Name.Space < attrs[j].Name.Space
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+}
+
+func (n *Node) isLeaf() bool {
+	return len(n.Children) == 0
+}
+
+// child returns the first child of n named local, preferring the namespace of n.
+func (n *Node) child(local string) *Node {
+	var found *Node
+	for _, c := range n.Children {
+		if c.Name.Local != local {
+			continue
+		}
+		if c.Name.Space == n.Name.Space {
+			return c
+		}
+		if found == nil {
+			found = c
+		}
+	}
+	return found
+}
+
+// copy returns a deep copy of n without its operation.
+func (n *Node) copy() *Node {
+	c := &Node{Name: n.Name, Attrs: n.Attrs, Text: n.Text}
+	for _, child := range n.Children {
+		c.Children = append(c.Children, child.copy())
+	}
+	return c
+}
+
+func attrsEqual(a, b []xml.Attr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns n as XML, declaring the namespaces it uses.
+func (n *Node) String() string {
+	var w xmlWriter
+	w.write(n, "")
+	return w.String()
+}
+
+// xmlWriter writes nodes declaring the default namespace wherever it changes
+// and a prefix for each attribute namespace.
+type xmlWriter struct {
+	bytes.Buffer
+	// declareNC declares the nc prefix of the base namespace on the
+	// outermost elements
+	declareNC bool
+	// ncPrefix is set when an ancestor declares the nc prefix
+	ncPrefix bool
+}
+
+func (w *xmlWriter) write(n *Node, parentNS string) {
+	w.WriteString("<" + n.Name.Local)
+	if n.Name.Space != parentNS {
+		w.WriteString(` xmlns="`)
+		xml.EscapeText(w, []byte(n.Name.Space))
+		w.WriteString(`"`)
+	}
+	if w.declareNC && !w.ncPrefix {
+		w.WriteString(` xmlns:nc="` + baseNS + `"`)
+		w.ncPrefix = true
+		defer func() { w.ncPrefix = false }()
+	}
+
+	prefixes := map[string]string{}
+	for _, a := range n.Attrs {
+		w.WriteString(" " + w.attrName(a.Name, prefixes) + `="`)
+		xml.EscapeText(w, []byte(a.Value))
+		w.WriteString(`"`)
+	}
+	if n.op != "" {
+		w.WriteString(" " + w.attrName(xml.Name{Space: baseNS, Local: "operation"}, prefixes) + `="` + n.op + `"`)
+	}
+
+	if n.isLeaf() && n.Text == "" {
+		w.WriteString("/>")
+		return
+	}
+	w.WriteString(">")
+	if n.isLeaf() {
+		xml.EscapeText(w, []byte(n.Text))
+	}
+	for _, c := range n.Children {
+		w.write(c, n.Name.Space)
+	}
+	w.WriteString("</" + n.Name.Local + ">")
+}
+
+// attrName returns the qualified name of an attribute, declaring its prefix
+// on the element being written if needed.
+func (w *xmlWriter) attrName(name xml.Name, prefixes map[string]string) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	if name.Space == baseNS && w.ncPrefix {
+		return "nc:" + name.Local
+	}
+	prefix, ok := prefixes[name.Space]
+	if !ok {
+		prefix = fmt.Sprintf("ns%d", len(prefixes))
+		prefixes[name.Space] = prefix
+		w.WriteString(" xmlns:" + prefix + `="`)
+		xml.EscapeText(w, []byte(name.Space))
+		w.WriteString(`"`)
+	}
+	return prefix + ":" + name.Local
+}
@@ -0,0 +1,60 @@
+// Go NETCONF Client - Configuration diff
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package configdiff
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	n, err := Parse([]byte(`<?xml version="1.0"?>
+<!-- running -->
+<system xmlns="urn:example:system" xmlns:ex="urn:example:ext" b="2" ex:a="1">
+    <host-name>  r1
+    </host-name>
+    <ex:contact/>
+</system>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &Node{
+		Name: xml.Name{Space: "urn:example:system", Local: "system"},
+		Attrs: []xml.Attr{
+			{Name: xml.Name{Local: "b"}, Value: "2"},
+			{Name: xml.Name{Space: "urn:example:ext", Local: "a"}, Value: "1"},
+		},
+		Children: []*Node{
+			{Name: xml.Name{Space: "urn:example:system", Local: "host-name"}, Text: "r1"},
+			{Name: xml.Name{Space: "urn:example:ext", Local: "contact"}},
+		},
+	}
+	if diff := cmp.Diff(expected, n, cmp.AllowUnexported(Node{})); diff != "" {
+		t.Errorf("node mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, doc := range []string{"", "<a/><b/>", "<a>"} {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("%q: expected an error", doc)
+		}
+	}
+}
+
+func TestNodeString(t *testing.T) {
+	n, err := Parse([]byte(`<a:system xmlns:a="urn:example:system" xmlns:ex="urn:example:ext" ex:a="1"><a:name>r&amp;1</a:name><other xmlns=""/></a:system>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `<system xmlns="urn:example:system" xmlns:ns0="urn:example:ext" ns0:a="1"><name>r&amp;1</name><other xmlns=""/></system>`
+	if got := n.String(); got != expected {
+		t.Errorf("got %s, expected %s", got, expected)
+	}
+}
@@ -0,0 +1,381 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Credentials are the username and authentication methods used to log in to
+// a device.
+type Credentials struct {
+	User string
+	Auth []ssh.AuthMethod
+	// Certificates are the client certificates presented over TLS, which
+	// the device maps to a username
+	Certificates []tls.Certificate
+}
+
+// CredentialProvider provides the credentials of a target each time it is
+// dialed, so rotated secrets are picked up without rebuilding configs.
+// Providers doing I/O give up when ctx is done, e.g. when the dial is
+// cancelled.
+type CredentialProvider interface {
+	Credentials(ctx context.Context, target string) (*Credentials, error)
+}
+
+// CredentialProviderFunc is an adapter allowing a function to be used as a
+// CredentialProvider.
+type CredentialProviderFunc func(ctx context.Context, target string) (*Credentials, error)
+
+// Credentials calls f(ctx, target)
+func (f CredentialProviderFunc) Credentials(ctx context.Context, target string) (*Credentials, error) {
+	return f(ctx, target)
+}
+
+// passwordCredentials authenticates with a password, also answering the
+// password prompts of keyboard-interactive authentication
+func passwordCredentials(user string, password string) *Credentials {
+	return &Credentials{
+		User: user,
+		Auth: SSHAuthMethods(SSHAuthOptions{Password: password}),
+	}
+}
+
+// StaticCredentials provides the same username and password for every
+// target.
+type StaticCredentials struct {
+	User     string
+	Password string
+}
+
+// Credentials returns the static credentials
+func (c StaticCredentials) Credentials(ctx context.Context, target string) (*Credentials, error) {
+	return passwordCredentials(c.User, c.Password), nil
+}
+
+// EnvCredentials provides the username and password held by environment
+// variables, NETCONF_USERNAME and NETCONF_PASSWORD by default.
+type EnvCredentials struct {
+	UserVar     string
+	PasswordVar string
+}
+
+// Credentials reads the credentials from the environment
+func (c EnvCredentials) Credentials(ctx context.Context, target string) (*Credentials, error) {
+	userVar, passwordVar := c.UserVar, c.PasswordVar
+	if userVar == "" {
+		userVar = "NETCONF_USERNAME"
+	}
+	if passwordVar == "" {
+		passwordVar = "NETCONF_PASSWORD"
+	}
+
+	user, ok := os.LookupEnv(userVar)
+	if !ok {
+		return nil, fmt.Errorf("netconf: %s is not set", userVar)
+	}
+	return passwordCredentials(user, os.Getenv(passwordVar)), nil
+}
+
+// FileCredentials provides the username and password held by a file of
+// "username=..." and "password=..." lines, read each time so it can be
+// rewritten when the password rotates.  Empty lines and lines starting with #
+// are ignored.
+type FileCredentials struct {
+	Path string
+}
+
+// Credentials reads the credentials from the file
+func (c FileCredentials) Credentials(ctx context.Context, target string) (*Credentials, error) {
+	buf, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var user, password string
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("netconf: invalid line in %s", c.Path)
+		}
+		switch strings.TrimSpace(line[:i]) {
+		case "username":
+			user = strings.TrimSpace(line[i+1:])
+		case "password":
+			password = strings.TrimSpace(line[i+1:])
+		}
+	}
+
+	if user == "" {
+		return nil, fmt.Errorf("netconf: no username in %s", c.Path)
+	}
+	return passwordCredentials(user, password), nil
+}
+
+// NetrcCredentials provides the login and password of the netrc file machine
+// entry matching the target host, falling back to the default entry.  Path
+// defaults to ~/.netrc.
+type NetrcCredentials struct {
+	Path string
+}
+
+// Credentials looks up the target in the netrc file
+func (c NetrcCredentials) Credentials(ctx context.Context, target string) (*Credentials, error) {
+	path := c.Path
+	if path == "" {
+		path = expandHome("~/.netrc")
+	}
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	host := target
+	if h, _, err := net.SplitHostPort(target); err == nil {
+		host = h
+	}
+
+	fields := netrcFields(buf)
+	var machine, fallback *Credentials
+	var current *Credentials
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			current = nil
+			if i+1 < len(fields) && fields[i+1] == host && machine == nil {
+				machine = &Credentials{}
+				current = machine
+			}
+			i++
+		case "default":
+			current = nil
+			if fallback == nil {
+				fallback = &Credentials{}
+				current = fallback
+			}
+		case "login", "password", "account":
+			if i+1 >= len(fields) {
+				return nil, fmt.Errorf("netconf: missing %s value in %s", fields[i], path)
+			}
+			if current != nil && fields[i] == "login" {
+				current.User = fields[i+1]
+			}
+			if current != nil && fields[i] == "password" {
+				current.Auth = SSHAuthMethods(SSHAuthOptions{Password: fields[i+1]})
+			}
+			i++
+		case "macdef":
+			// The macro body was dropped by netrcFields
+			i++
+		}
+	}
+
+	if machine != nil {
+		return machine, nil
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	return nil, fmt.Errorf("netconf: no netrc entry for %s in %s", host, path)
+}
+
+// netrcFields returns the tokens of a netrc file without the bodies of its
+// macro definitions, which run from the line after macdef to the next empty
+// line
+func netrcFields(buf []byte) []string {
+	var fields []string
+	macro := false
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		words := strings.Fields(scanner.Text())
+		if macro {
+			macro = len(words) > 0
+			continue
+		}
+
+		for i := 0; i < len(words) && !macro; i++ {
+			fields = append(fields, words[i])
+			// The macro name ends the line
+			if words[i] == "macdef" {
+				if i+1 < len(words) {
+					fields = append(fields, words[i+1])
+				}
+				macro = true
+			}
+		}
+	}
+	return fields
+}
+
+// ExecCredentials provides the credentials returned by a helper program,
+// such as a wrapper around a secrets vault.  The program is run with its
+// arguments followed by the target and must print a JSON object with
+// "username" and "password" members.
+type ExecCredentials struct {
+	Command string
+	Args    []string
+	// Timeout bounds the run time of the program, 30 seconds by default
+	Timeout time.Duration
+}
+
+// Credentials runs the helper program for target, killing it when ctx is
+// done
+func (c ExecCredentials) Credentials(ctx context.Context, target string) (*Credentials, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Args may have spare capacity shared by concurrent dials
+	args := append(append([]string(nil), c.Args...), target)
+	cmd := exec.CommandContext(ctx, c.Command, args...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("netconf: credential helper %s: %v", filepath.Base(c.Command), err)
+	}
+
+	var creds struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return nil, fmt.Errorf("netconf: credential helper %s: %v", filepath.Base(c.Command), err)
+	}
+	return passwordCredentials(creds.Username, creds.Password), nil
+}
+
+// SSHConfigCredentials returns a copy of config using the user and
+// authentication methods provided for target.
+func SSHConfigCredentials(ctx context.Context, config *ssh.ClientConfig, p CredentialProvider, target string) (*ssh.ClientConfig, error) {
+	creds, err := p.Credentials(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *config
+	c.User = creds.User
+	c.Auth = creds.Auth
+	return &c, nil
+}
+
+// DialSSHCredentials creates a new NETCONF session using a SSH Transport,
+// authenticating with the credentials provided for target.  config holds the
+// other settings such as the HostKeyCallback.  See TransportSSH.Dial for
+// arguments.
+func DialSSHCredentials(target string, config *ssh.ClientConfig, p CredentialProvider) (*Session, error) {
+	c, err := SSHConfigCredentials(context.Background(), config, p, target)
+	if err != nil {
+		return nil, err
+	}
+	return DialSSH(target, c)
+}
+
+// DialSSHCredentialsContext creates a new NETCONF session using a SSH
+// Transport, authenticating with the credentials provided for target and
+// giving up when ctx is done, the provider included.  See DialSSHContext.
+func DialSSHCredentialsContext(ctx context.Context, target string, config *ssh.ClientConfig, p CredentialProvider) (*Session, error) {
+	c, err := SSHConfigCredentials(ctx, config, p, target)
+	if err != nil {
+		return nil, err
+	}
+	return DialSSHContext(ctx, target, c)
+}
+
+// SSHCredentialsDialer returns a dial function for NewPool, NewFanOut or
+// NewManagedSession consulting the provider on each dial, e.g.
+//
+//	pool := NewPool(SSHCredentialsDialer(config, vault))
+func SSHCredentialsDialer(config *ssh.ClientConfig, p CredentialProvider) func(ctx context.Context, target string) (*Session, error) {
+	return func(ctx context.Context, target string) (*Session, error) {
+		return DialSSHCredentialsContext(ctx, target, config, p)
+	}
+}
+
+// DialSSHJumpCredentials creates a new NETCONF session through jump hosts as
+// DialSSHJump, authenticating with the credentials provided for target.  The
+// jump hosts without a Config use config with the credentials provided for
+// their Target.
+func DialSSHJumpCredentials(target string, config *ssh.ClientConfig, p CredentialProvider, jumps ...SSHJumpHost) (*Session, error) {
+	ctx := context.Background()
+	c, err := SSHConfigCredentials(ctx, config, p, target)
+	if err != nil {
+		return nil, err
+	}
+
+	jumps = append([]SSHJumpHost(nil), jumps...)
+	for i, jump := range jumps {
+		if jump.Config != nil {
+			continue
+		}
+		if jumps[i].Config, err = SSHConfigCredentials(ctx, config, p, jump.Target); err != nil {
+			return nil, err
+		}
+	}
+	return DialSSHJump(target, c, jumps...)
+}
+
+// TLSConfigCredentials returns a copy of config presenting the client
+// certificates provided for target.
+func TLSConfigCredentials(ctx context.Context, config *tls.Config, p CredentialProvider, target string) (*tls.Config, error) {
+	creds, err := p.Credentials(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if len(creds.Certificates) == 0 {
+		return nil, fmt.Errorf("netconf: no client certificate provided for %s", target)
+	}
+
+	c := config.Clone()
+	c.Certificates = creds.Certificates
+	return c, nil
+}
+
+// DialTLSCredentials creates a new NETCONF session using a TLS Transport,
+// presenting the client certificates provided for target.  See
+// TransportTLS.Dial for arguments.
+func DialTLSCredentials(target string, config *tls.Config, p CredentialProvider) (*Session, error) {
+	c, err := TLSConfigCredentials(context.Background(), config, p, target)
+	if err != nil {
+		return nil, err
+	}
+	return DialTLS(target, c)
+}
+
+// DialTLSCredentialsContext creates a new NETCONF session using a TLS
+// Transport, presenting the client certificates provided for target and
+// giving up when ctx is done, the provider included.  See DialTLSContext.
+func DialTLSCredentialsContext(ctx context.Context, target string, config *tls.Config, p CredentialProvider) (*Session, error) {
+	c, err := TLSConfigCredentials(ctx, config, p, target)
+	if err != nil {
+		return nil, err
+	}
+	return DialTLSContext(ctx, target, c)
+}
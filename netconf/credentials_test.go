@@ -0,0 +1,319 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCredentialProviders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netconf")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	credsFile := writeTestFile(t, dir, "creds", "# rotated daily\nusername = file\npassword = file-secret\n")
+	netrc := writeTestFile(t, dir, "netrc", `
+machine ftp.example.org login ftp macdef init
+cd /pub
+machine r1.example.org login macro password macro-secret
+
+machine r1.example.org login netrc password netrc-secret
+default login fallback password fallback-secret
+`)
+
+	os.Setenv("NETCONF_TEST_USERNAME", "env")
+	os.Setenv("NETCONF_TEST_PASSWORD", "env-secret")
+	defer os.Unsetenv("NETCONF_TEST_USERNAME")
+	defer os.Unsetenv("NETCONF_TEST_PASSWORD")
+
+	tt := []struct {
+		name     string
+		provider CredentialProvider
+		target   string
+		user     string
+		password string
+		err      bool
+	}{
+		{
+			name:     "static",
+			provider: StaticCredentials{User: "static", Password: "static-secret"},
+			user:     "static",
+			password: "static-secret",
+		},
+		{
+			name: "func",
+			provider: CredentialProviderFunc(func(ctx context.Context, target string) (*Credentials, error) {
+				return &Credentials{User: "func", Auth: []ssh.AuthMethod{ssh.Password("func-secret")}}, nil
+			}),
+			user:     "func",
+			password: "func-secret",
+		},
+		{
+			name:     "env",
+			provider: EnvCredentials{UserVar: "NETCONF_TEST_USERNAME", PasswordVar: "NETCONF_TEST_PASSWORD"},
+			user:     "env",
+			password: "env-secret",
+		},
+		{
+			name:     "envUnset",
+			provider: EnvCredentials{UserVar: "NETCONF_TEST_UNSET"},
+			err:      true,
+		},
+		{
+			name:     "file",
+			provider: FileCredentials{Path: credsFile},
+			user:     "file",
+			password: "file-secret",
+		},
+		{
+			name:     "netrcMachine",
+			provider: NetrcCredentials{Path: netrc},
+			target:   "r1.example.org:830",
+			user:     "netrc",
+			password: "netrc-secret",
+		},
+		{
+			name:     "netrcDefault",
+			provider: NetrcCredentials{Path: netrc},
+			target:   "r2.example.org:830",
+			user:     "fallback",
+			password: "fallback-secret",
+		},
+		{
+			name: "exec",
+			provider: ExecCredentials{
+				Command: "sh",
+				Args:    []string{"-c", `printf '{"username":"%s","password":"exec-secret"}' "$0"`},
+			},
+			target:   "exec",
+			user:     "exec",
+			password: "exec-secret",
+		},
+		{
+			name:     "execFailure",
+			provider: ExecCredentials{Command: "sh", Args: []string{"-c", "exit 1"}},
+			err:      true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestSSHServer(t, &ssh.ServerConfig{
+				PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+					if conn.User() != tc.user || string(password) != tc.password {
+						return nil, fmt.Errorf("invalid credentials %s/%s", conn.User(), password)
+					}
+					return nil, nil
+				},
+			})
+			defer server.Close()
+
+			target := tc.target
+			if target == "" {
+				target = server.Addr()
+			}
+			config, err := SSHConfigCredentials(context.Background(), &ssh.ClientConfig{HostKeyCallback: ssh.InsecureIgnoreHostKey()}, tc.provider, target)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			s, err := DialSSH(server.Addr(), config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			s.Close()
+		})
+	}
+}
+
+func TestDialSSHCredentials(t *testing.T) {
+	passwords := []string{"first", "second"}
+	var dials int
+
+	server := newTestSSHServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != passwords[dials] {
+				return nil, fmt.Errorf("invalid password")
+			}
+			return nil, nil
+		},
+	})
+	defer server.Close()
+
+	// The provider is consulted on every dial, picking up rotated passwords
+	provider := CredentialProviderFunc(func(ctx context.Context, target string) (*Credentials, error) {
+		if target != server.Addr() {
+			t.Errorf("got target %s, expected %s", target, server.Addr())
+		}
+		return passwordCredentials("test", passwords[dials]), nil
+	})
+	config := &ssh.ClientConfig{HostKeyCallback: ssh.InsecureIgnoreHostKey()}
+
+	for dials = range passwords {
+		s, err := DialSSHCredentials(server.Addr(), config, provider)
+		if err != nil {
+			t.Fatalf("dial %d: unexpected error: %v", dials, err)
+		}
+		s.Close()
+	}
+	if config.User != "" || config.Auth != nil {
+		t.Errorf("config was modified")
+	}
+}
+
+func TestExecCredentialsArgs(t *testing.T) {
+	// Spare capacity in Args must not be shared between dials
+	args := make([]string, 2, 3)
+	args[0], args[1] = "-c", `printf '{"username":"%s"}' "$0"`
+	c := ExecCredentials{Command: "sh", Args: args}
+
+	creds, err := c.Credentials(context.Background(), "r1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.User != "r1" {
+		t.Errorf("got user %s, expected r1", creds.User)
+	}
+	if spare := args[:3][2]; spare != "" {
+		t.Errorf("target %q written to the Args backing array", spare)
+	}
+}
+
+func TestExecCredentialsContext(t *testing.T) {
+	c := ExecCredentials{Command: "sh", Args: []string{"-c", "exec sleep 10"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.Credentials(ctx, "r1"); err == nil {
+		t.Errorf("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("helper ran for %s after ctx was done", elapsed)
+	}
+}
+
+// userPasswordServer starts a test SSH server accepting a single user and
+// password
+func userPasswordServer(t *testing.T, user string, password string) *testSSHServer {
+	return newTestSSHServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, p []byte) (*ssh.Permissions, error) {
+			if conn.User() != user || string(p) != password {
+				return nil, fmt.Errorf("invalid credentials %s/%s", conn.User(), p)
+			}
+			return nil, nil
+		},
+	})
+}
+
+func TestSSHCredentialsDialer(t *testing.T) {
+	server := userPasswordServer(t, "test", "secret")
+	defer server.Close()
+
+	provider := StaticCredentials{User: "test", Password: "secret"}
+	dial := SSHCredentialsDialer(&ssh.ClientConfig{HostKeyCallback: ssh.InsecureIgnoreHostKey()}, provider)
+
+	s, err := dial(context.Background(), server.Addr())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := dial(ctx, server.Addr()); err != context.Canceled {
+		t.Errorf("got error %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestDialSSHJumpCredentials(t *testing.T) {
+	bastion := userPasswordServer(t, "jump", "jump-secret")
+	defer bastion.Close()
+	device := userPasswordServer(t, "device", "device-secret")
+	defer device.Close()
+
+	provider := CredentialProviderFunc(func(ctx context.Context, target string) (*Credentials, error) {
+		switch target {
+		case bastion.Addr():
+			return passwordCredentials("jump", "jump-secret"), nil
+		case device.Addr():
+			return passwordCredentials("device", "device-secret"), nil
+		}
+		return nil, fmt.Errorf("unknown target %s", target)
+	})
+	config := &ssh.ClientConfig{HostKeyCallback: ssh.InsecureIgnoreHostKey()}
+
+	jumps := []SSHJumpHost{{Target: bastion.Addr()}}
+	s, err := DialSSHJumpCredentials(device.Addr(), config, provider, jumps...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.Close()
+	if jumps[0].Config != nil {
+		t.Errorf("jump hosts were modified")
+	}
+}
+
+func TestDialTLSCredentials(t *testing.T) {
+	serverCert, pool := newTestCertificate(t)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAnyClientCert,
+	})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestNetconf(c)
+		}
+	}()
+
+	clientCert, _ := newTestCertificate(t)
+	provider := CredentialProviderFunc(func(ctx context.Context, target string) (*Credentials, error) {
+		return &Credentials{Certificates: []tls.Certificate{clientCert}}, nil
+	})
+	config := &tls.Config{RootCAs: pool}
+
+	s, err := DialTLSCredentials(l.Addr().String(), config, provider)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.Exec(MethodLock("candidate")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if config.Certificates != nil {
+		t.Errorf("config was modified")
+	}
+
+	_, err = DialTLSCredentials(l.Addr().String(), config, StaticCredentials{User: "test"})
+	if err == nil || err.Error() != "netconf: no client certificate provided for "+l.Addr().String() {
+		t.Errorf("unexpected error %v", err)
+	}
+}
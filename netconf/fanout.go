@@ -0,0 +1,190 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// FanOut runs the same operation against many devices with a bounded number
+// of devices in flight.
+type FanOut struct {
+	// Concurrency is the maximum number of devices operated at once, 50 by
+	// default.
+	Concurrency int
+	// Timeout bounds the operation of each device, dial included.  No
+	// timeout is applied when zero.
+	Timeout time.Duration
+
+	dial func(ctx context.Context, target string) (*Session, error)
+}
+
+// FanOutResult is the outcome of the operation on a device.
+type FanOutResult struct {
+	Target   string
+	Reply    *RPCReply
+	Err      error
+	Duration time.Duration
+}
+
+// FanOutStats aggregates the results of a FanOutJob.
+type FanOutStats struct {
+	Total     int
+	Completed int
+	Succeeded int
+	Failed    int
+	// Duration is the time elapsed since the job started, until the last
+	// device completed once the job is done
+	Duration time.Duration
+}
+
+// FanOutJob tracks the operations started by FanOut.Run or FanOut.Exec.
+type FanOutJob struct {
+	results chan FanOutResult
+	done    chan struct{}
+	start   time.Time
+
+	mu    sync.Mutex
+	stats FanOutStats
+}
+
+// NewFanOut creates a FanOut opening sessions with dial.
+func NewFanOut(dial func(ctx context.Context, target string) (*Session, error)) *FanOut {
+	return &FanOut{dial: dial}
+}
+
+// NewSSHFanOut creates a FanOut opening SSH sessions.  See TransportSSH.Dial
+// for config.
+func NewSSHFanOut(config *ssh.ClientConfig) *FanOut {
+	return NewFanOut(func(ctx context.Context, target string) (*Session, error) {
+		return DialSSHContext(ctx, target, config)
+	})
+}
+
+// Exec executes an RPC method or methods on every target.
+func (f *FanOut) Exec(ctx context.Context, targets []string, methods ...RPCMethod) *FanOutJob {
+	return f.Run(ctx, targets, func(ctx context.Context, target string, s *Session) (*RPCReply, error) {
+		return s.ExecContext(ctx, methods...)
+	})
+}
+
+// Run dials every target and calls fn with the target and its session, which
+// is closed once fn returns.  Targets not started when ctx is done fail with
+// ctx.Err().
+func (f *FanOut) Run(ctx context.Context, targets []string, fn func(ctx context.Context, target string, s *Session) (*RPCReply, error)) *FanOutJob {
+	j := &FanOutJob{
+		// Buffered so workers never wait for the results to be read
+		results: make(chan FanOutResult, len(targets)),
+		done:    make(chan struct{}),
+		start:   time.Now(),
+		stats:   FanOutStats{Total: len(targets)},
+	}
+
+	concurrency := f.Concurrency
+	if concurrency <= 0 {
+		concurrency = 50
+	}
+
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(targets); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range queue {
+				j.add(f.run(ctx, target, fn))
+			}
+		}()
+	}
+
+	go func() {
+		for _, target := range targets {
+			queue <- target
+		}
+		close(queue)
+		wg.Wait()
+
+		j.mu.Lock()
+		j.stats.Duration = time.Since(j.start)
+		j.mu.Unlock()
+		close(j.results)
+		close(j.done)
+	}()
+
+	return j
+}
+
+func (f *FanOut) run(ctx context.Context, target string, fn func(ctx context.Context, target string, s *Session) (*RPCReply, error)) FanOutResult {
+	start := time.Now()
+	result := FanOutResult{Target: target}
+
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+
+	s, err := f.dial(ctx, target)
+	if err != nil {
+		result.Err = err
+		result.Duration = time.Since(start)
+		return result
+	}
+	defer s.Close()
+
+	result.Reply, result.Err = fn(ctx, target, s)
+	result.Duration = time.Since(start)
+	return result
+}
+
+func (j *FanOutJob) add(result FanOutResult) {
+	j.mu.Lock()
+	j.stats.Completed++
+	if result.Err != nil {
+		j.stats.Failed++
+	} else {
+		j.stats.Succeeded++
+	}
+	j.mu.Unlock()
+
+	j.results <- result
+}
+
+// Results returns the channel receiving the result of each device as it
+// completes, closed once every device completed.
+func (j *FanOutJob) Results() <-chan FanOutResult {
+	return j.results
+}
+
+// Stats returns the aggregate statistics of the devices completed so far.
+func (j *FanOutJob) Stats() FanOutStats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stats := j.stats
+	select {
+	case <-j.done:
+	default:
+		stats.Duration = time.Since(j.start)
+	}
+	return stats
+}
+
+// Wait waits for every device to complete and returns the final statistics.
+// The results are still available from Results.
+func (j *FanOutJob) Wait() FanOutStats {
+	<-j.done
+	return j.Stats()
+}
@@ -0,0 +1,132 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFanOutExec(t *testing.T) {
+	var inFlight, maxInFlight int32
+	f := NewFanOut(func(ctx context.Context, target string) (*Session, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		switch target {
+		case "unreachable":
+			return nil, fmt.Errorf("dial tcp %s: connection refused", target)
+		case "hung":
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		s, _ := newSessionTest(testOkReply)
+		return s, nil
+	})
+	f.Concurrency = 3
+	f.Timeout = 50 * time.Millisecond
+
+	var targets []string
+	for i := 0; i < 10; i++ {
+		targets = append(targets, fmt.Sprintf("r%d", i))
+	}
+	targets = append(targets, "unreachable", "hung")
+
+	job := f.Exec(context.Background(), targets, MethodGetConfig("running"))
+
+	var failed []string
+	results := 0
+	for result := range job.Results() {
+		results++
+		if result.Err != nil {
+			failed = append(failed, result.Target)
+			continue
+		}
+		if result.Reply == nil {
+			t.Errorf("%s: missing reply", result.Target)
+		}
+	}
+	sort.Strings(failed)
+
+	if results != len(targets) {
+		t.Errorf("got %d results, expected %d", results, len(targets))
+	}
+	if expected := []string{"hung", "unreachable"}; !cmp.Equal(failed, expected) {
+		t.Errorf("unexpected failures:\n%s", cmp.Diff(expected, failed))
+	}
+	if max := atomic.LoadInt32(&maxInFlight); max > 3 {
+		t.Errorf("got %d devices in flight, expected at most 3", max)
+	}
+
+	stats := job.Wait()
+	expected := FanOutStats{Total: 12, Completed: 12, Succeeded: 10, Failed: 2, Duration: stats.Duration}
+	if !cmp.Equal(stats, expected) {
+		t.Errorf("unexpected stats:\n%s", cmp.Diff(expected, stats))
+	}
+}
+
+func TestFanOutRun(t *testing.T) {
+	f := NewFanOut(func(ctx context.Context, target string) (*Session, error) {
+		s, _ := newSessionTest(testOkReply)
+		s.SessionID = len(target)
+		return s, nil
+	})
+
+	job := f.Run(context.Background(), []string{"r1", "r10"}, func(ctx context.Context, target string, s *Session) (*RPCReply, error) {
+		if s.SessionID != len(target) {
+			return nil, fmt.Errorf("got session %d for %s", s.SessionID, target)
+		}
+		return s.ExecContext(ctx, MethodGetConfig("running"))
+	})
+
+	for result := range job.Results() {
+		if result.Err != nil {
+			t.Errorf("%s: unexpected error: %v", result.Target, result.Err)
+		}
+	}
+}
+
+func TestFanOutRunCancel(t *testing.T) {
+	f := NewFanOut(func(ctx context.Context, target string) (*Session, error) {
+		s, _ := newSessionTest(testOkReply)
+		return s, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	job := f.Run(ctx, []string{"r1", "r2"}, func(ctx context.Context, target string, s *Session) (*RPCReply, error) {
+		calls++
+		return nil, nil
+	})
+
+	for result := range job.Results() {
+		if result.Err != context.Canceled {
+			t.Errorf("%s: got error %v, expected %v", result.Target, result.Err, context.Canceled)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("got %d calls, expected 0", calls)
+	}
+	if stats := job.Wait(); stats.Failed != 2 {
+		t.Errorf("got %d failures, expected 2", stats.Failed)
+	}
+}
@@ -0,0 +1,430 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MessageDirection tells whether a message was sent or received.
+type MessageDirection int
+
+// Directions of a MessageEvent.
+const (
+	MessageSent MessageDirection = iota
+	MessageReceived
+)
+
+func (d MessageDirection) String() string {
+	switch d {
+	case MessageSent:
+		return "sent"
+	case MessageReceived:
+		return "received"
+	}
+	return fmt.Sprintf("MessageDirection(%d)", int(d))
+}
+
+// MessageEvent describes a message sent or received by a Session.
+type MessageEvent struct {
+	Direction MessageDirection
+	SessionID int
+	// MessageID is the message-id of the rpc, empty for hellos
+	MessageID string
+	// Message is the exact XML sent or received, except for the received
+	// hello which is re-encoded
+	Message []byte
+	Bytes   int
+	// Duration is the time taken to send the message, or the time elapsed
+	// between sending the rpc and receiving its reply
+	Duration time.Duration
+	// Err is the error sending or receiving the message, if any
+	Err error
+}
+
+// MessageHook is called for every message sent or received by a Session, or
+// through a HookTransport.  Hooks must not modify the event, which is shared
+// between hooks.
+type MessageHook interface {
+	Message(e *MessageEvent)
+}
+
+// MessageHookFunc is an adapter allowing a function to be used as a
+// MessageHook.
+type MessageHookFunc func(e *MessageEvent)
+
+// Message calls f(e)
+func (f MessageHookFunc) Message(e *MessageEvent) {
+	f(e)
+}
+
+// DefaultMessageHooks sets the hooks of new sessions.
+var DefaultMessageHooks []MessageHook
+
+// notify calls the hooks of the session with e
+func (s *Session) notify(e *MessageEvent) {
+	if len(s.Hooks) == 0 {
+		return
+	}
+
+	e.SessionID = s.SessionID
+	e.Bytes = len(e.Message)
+	for _, h := range s.Hooks {
+		h.Message(e)
+	}
+}
+
+// Redactor replaces the content of secret elements of messages, such as
+// passwords in configurations, before they are logged.  The secrets of the
+// Junos text and set configurations held by configuration-text and
+// configuration-set elements are redacted too.
+type Redactor struct {
+	// Elements are the names of the elements to redact wherever they are
+	Elements []string
+	// Paths are element paths separated by /, matching the elements whose
+	// ancestors end with the path, e.g. root-authentication/encrypted-password.
+	// Paths starting with / are anchored at the root element, e.g.
+	// /rpc/edit-config/config.
+	Paths []string
+	// Statements are the keywords of the text and set configuration
+	// statements whose value is redacted, e.g. encrypted-password.  The
+	// quoted values of the lines Junos marks with ## SECRET-DATA are
+	// redacted whatever their statement.  Statements must not change once
+	// the Redactor is used.
+	Statements []string
+	// Replacement is the redacted content, REDACTED by default
+	Replacement string
+
+	// statement matches the Statements and their value, compiled on first
+	// use
+	once      sync.Once
+	statement *regexp.Regexp
+}
+
+// textConfigElements are the elements holding Junos text and set
+// configurations
+var textConfigElements = map[string]bool{
+	"configuration-text": true,
+	"configuration-set":  true,
+}
+
+// DefaultRedactor redacts the usual secrets of device configurations.
+var DefaultRedactor = &Redactor{
+	Elements: []string{
+		"encrypted-password",
+		"plain-text-password-value",
+		"password",
+		"secret",
+		"pre-shared-key",
+		"authentication-key",
+	},
+	Statements: []string{
+		"encrypted-password",
+		"password",
+		"secret",
+		"ascii-text",
+		"hexadecimal",
+		"authentication-key",
+	},
+}
+
+// Redact returns msg with the content of the matching elements replaced.
+// msg is returned as-is when nothing is redacted or it is not well-formed.
+func (r *Redactor) Redact(msg []byte) []byte {
+	replacement := r.Replacement
+	if replacement == "" {
+		replacement = "REDACTED"
+	}
+
+	type span struct {
+		start, end  int64
+		replacement []byte
+	}
+	var spans []span
+
+	var stack []string
+	// Depth of the redacted element in stack, -1 outside of one
+	redacting := -1
+	// Depth of the text configuration element in stack, -1 outside of one
+	textConfig := -1
+	var start int64
+
+	d := xml.NewDecoder(bytes.NewReader(msg))
+	for {
+		// The offset before the token is where the end tag of a redacted
+		// element or a text starts
+		offset := d.InputOffset()
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return msg
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, tok.Name.Local)
+			if redacting < 0 && r.matches(stack) {
+				redacting = len(stack)
+				start = d.InputOffset()
+			}
+			if textConfig < 0 && textConfigElements[tok.Name.Local] {
+				textConfig = len(stack)
+			}
+		case xml.EndElement:
+			if len(stack) == redacting {
+				// Empty and self-closing elements have nothing to redact
+				if offset > start {
+					spans = append(spans, span{start, offset, []byte(replacement)})
+				}
+				redacting = -1
+			}
+			if len(stack) == textConfig {
+				textConfig = -1
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if redacting >= 0 || textConfig < 0 {
+				continue
+			}
+			end := d.InputOffset()
+			if text := r.redactText(msg[offset:end], replacement); text != nil {
+				spans = append(spans, span{offset, end, text})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return msg
+	}
+
+	var buf bytes.Buffer
+	var pos int64
+	for _, s := range spans {
+		buf.Write(msg[pos:s.start])
+		buf.Write(s.replacement)
+		pos = s.end
+	}
+	buf.Write(msg[pos:])
+	return buf.Bytes()
+}
+
+// quotedValue matches a double quoted value, its quotes possibly escaped
+const quotedValue = `"(?:[^"\\\n]|\\.)*"|&quot;.*?&quot;|&#34;.*?&#34;`
+
+var (
+	secretDataLine = regexp.MustCompile(`(?m)^.*## SECRET-DATA[ \t\r]*$`)
+	quotedValueRE  = regexp.MustCompile(quotedValue)
+)
+
+// redactText returns the escaped text of a text or set configuration with
+// the values of the secret statements replaced, nil when nothing is
+// redacted.
+func (r *Redactor) redactText(text []byte, replacement string) []byte {
+	quote := func(value []byte) []byte {
+		for _, q := range []string{`"`, "&quot;", "&#34;"} {
+			if bytes.HasPrefix(value, []byte(q)) {
+				return []byte(q + replacement + q)
+			}
+		}
+		return []byte(replacement)
+	}
+
+	redacted := secretDataLine.ReplaceAllFunc(text, func(line []byte) []byte {
+		return quotedValueRE.ReplaceAllFunc(line, quote)
+	})
+
+	if statement := r.statementRegexp(); statement != nil {
+		redacted = statement.ReplaceAllFunc(redacted, func(m []byte) []byte {
+			sub := statement.FindSubmatchIndex(m)
+			var buf bytes.Buffer
+			buf.Write(m[:sub[8]])
+			buf.Write(quote(m[sub[8]:sub[9]]))
+			return buf.Bytes()
+		})
+	}
+
+	if bytes.Equal(redacted, text) {
+		return nil
+	}
+	return redacted
+}
+
+// statementRegexp returns the regexp matching the Statements and their
+// value, nil when there are none
+func (r *Redactor) statementRegexp() *regexp.Regexp {
+	r.once.Do(func() {
+		if len(r.Statements) == 0 {
+			return
+		}
+		keywords := make([]string, len(r.Statements))
+		for i, s := range r.Statements {
+			keywords[i] = regexp.QuoteMeta(s)
+		}
+		// The keywords are quoted so the pattern always compiles
+		r.statement, _ = regexp.Compile(`(^|[\s{;])(` + strings.Join(keywords, "|") + `)(\s+)(` + quotedValue + `|[^\s;{}"&]+)`)
+	})
+	return r.statement
+}
+
+// matches reports whether the element at the top of stack is redacted
+func (r *Redactor) matches(stack []string) bool {
+	name := stack[len(stack)-1]
+	for _, e := range r.Elements {
+		if e == name {
+			return true
+		}
+	}
+
+	for _, p := range r.Paths {
+		anchored := strings.HasPrefix(p, "/")
+		path := strings.Split(strings.Trim(p, "/"), "/")
+		if len(path) > len(stack) || (anchored && len(path) != len(stack)) {
+			continue
+		}
+
+		tail := stack[len(stack)-len(path):]
+		match := true
+		for i := range path {
+			if path[i] != tail[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHook returns a MessageHook calling next with the messages redacted by
+// r, DefaultRedactor when nil.
+func RedactHook(r *Redactor, next MessageHook) MessageHook {
+	if r == nil {
+		r = DefaultRedactor
+	}
+	return MessageHookFunc(func(e *MessageEvent) {
+		redacted := *e
+		redacted.Message = r.Redact(e.Message)
+		next.Message(&redacted)
+	})
+}
+
+// HookTransport wraps a Transport calling Hooks with every message sent or
+// received through it, hellos included.  Unlike the hooks of a Session, it
+// sees the messages of any user of the Transport, such as ManagedSession
+// receiving notifications.  The Duration of a received message is the time
+// elapsed since the last message was sent.  Setting the same hooks on the
+// Session using the HookTransport reports the rpcs and replies twice.
+type HookTransport struct {
+	Transport
+	Hooks []MessageHook
+
+	mu        sync.Mutex
+	sessionID int
+	sent      time.Time
+}
+
+// NewHookTransport returns a HookTransport wrapping t.
+func NewHookTransport(t Transport, hooks ...MessageHook) *HookTransport {
+	return &HookTransport{Transport: t, Hooks: hooks}
+}
+
+// messageID matches the message-id attribute of an rpc or rpc-reply
+var messageID = regexp.MustCompile(`\smessage-id\s*=\s*["']([^"']*)["']`)
+
+// Send sends data through the wrapped Transport.
+func (t *HookTransport) Send(data []byte) error {
+	start := time.Now()
+	err := t.Transport.Send(data)
+	t.mu.Lock()
+	t.sent = start
+	t.mu.Unlock()
+
+	t.notify(&MessageEvent{Direction: MessageSent, MessageID: findMessageID(data), Message: data, Duration: time.Since(start), Err: err})
+	return err
+}
+
+// Receive receives a message from the wrapped Transport.
+func (t *HookTransport) Receive() ([]byte, error) {
+	msg, err := t.Transport.Receive()
+	t.mu.Lock()
+	sent := t.sent
+	t.mu.Unlock()
+
+	var d time.Duration
+	if !sent.IsZero() {
+		d = time.Since(sent)
+	}
+	t.notify(&MessageEvent{Direction: MessageReceived, MessageID: findMessageID(msg), Message: msg, Duration: d, Err: err})
+	return msg, err
+}
+
+// ReceiveHello receives the hello of the server from the wrapped Transport,
+// the session-id of the hello identifying the messages of the later events.
+func (t *HookTransport) ReceiveHello() (*HelloMessage, error) {
+	start := time.Now()
+	hello, err := t.Transport.ReceiveHello()
+
+	var msg []byte
+	if hello != nil {
+		t.mu.Lock()
+		t.sessionID = hello.SessionID
+		t.mu.Unlock()
+		msg, _ = xml.Marshal(hello)
+	}
+	t.notify(&MessageEvent{Direction: MessageReceived, Message: msg, Duration: time.Since(start), Err: err})
+	return hello, err
+}
+
+// SendHello sends the hello of the client through the wrapped Transport.
+func (t *HookTransport) SendHello(hello *HelloMessage) error {
+	start := time.Now()
+	err := t.Transport.SendHello(hello)
+	msg, _ := xml.Marshal(hello)
+	t.notify(&MessageEvent{Direction: MessageSent, Message: append([]byte(xml.Header), msg...), Duration: time.Since(start), Err: err})
+	return err
+}
+
+// Err returns the error marking the wrapped Transport dead, if any.
+func (t *HookTransport) Err() error {
+	return transportErr(t.Transport)
+}
+
+// notify calls the hooks of the transport with e
+func (t *HookTransport) notify(e *MessageEvent) {
+	if len(t.Hooks) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	e.SessionID = t.sessionID
+	t.mu.Unlock()
+	e.Bytes = len(e.Message)
+	for _, h := range t.Hooks {
+		h.Message(e)
+	}
+}
+
+// findMessageID returns the message-id of an rpc or rpc-reply, empty when
+// there is none
+func findMessageID(msg []byte) string {
+	if m := messageID.FindSubmatch(msg); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
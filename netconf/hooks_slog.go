@@ -0,0 +1,38 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package netconf
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHook returns a MessageHook logging every message to logger at level,
+// failed ones at error level.  Messages are logged as-is, wrap the hook with
+// RedactHook to hide secrets.
+func SlogHook(logger *slog.Logger, level slog.Level) MessageHook {
+	return MessageHookFunc(func(e *MessageEvent) {
+		attrs := []slog.Attr{
+			slog.String("direction", e.Direction.String()),
+			slog.Int("session_id", e.SessionID),
+			slog.String("message_id", e.MessageID),
+			slog.Int("bytes", e.Bytes),
+			slog.Duration("duration", e.Duration),
+			slog.String("message", string(e.Message)),
+		}
+
+		lvl := level
+		if e.Err != nil {
+			lvl = slog.LevelError
+			attrs = append(attrs, slog.String("error", e.Err.Error()))
+		}
+		logger.LogAttrs(context.Background(), lvl, "netconf message", attrs...)
+	})
+}
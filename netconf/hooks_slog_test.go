@@ -0,0 +1,51 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package netconf
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHook(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	hook := RedactHook(nil, SlogHook(logger, slog.LevelDebug))
+
+	hook.Message(&MessageEvent{
+		Direction: MessageSent,
+		SessionID: 42,
+		MessageID: "1",
+		Message:   []byte("<password>secret</password>"),
+		Bytes:     27,
+	})
+	hook.Message(&MessageEvent{Direction: MessageReceived, SessionID: 42, MessageID: "1", Err: io.EOF})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, expected 2:\n%s", len(lines), buf.String())
+	}
+	for _, s := range []string{"level=DEBUG", "direction=sent", "session_id=42", "message_id=1", "bytes=27", "REDACTED"} {
+		if !strings.Contains(lines[0], s) {
+			t.Errorf("%q not logged in %s", s, lines[0])
+		}
+	}
+	if strings.Contains(lines[0], "secret") {
+		t.Errorf("secret logged: %s", lines[0])
+	}
+	for _, s := range []string{"level=ERROR", "direction=received", "error=EOF"} {
+		if !strings.Contains(lines[1], s) {
+			t.Errorf("%q not logged in %s", s, lines[1])
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSessionHooks(t *testing.T) {
+	var events []MessageEvent
+	hook := MessageHookFunc(func(e *MessageEvent) { events = append(events, *e) })
+
+	defer func(hooks []MessageHook) { DefaultMessageHooks = hooks }(DefaultMessageHooks)
+	DefaultMessageHooks = []MessageHook{hook}
+
+	s, _ := newSessionTest(testOkReply)
+	reply, err := s.Exec(MethodLock("candidate"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("got %d events, expected 4", len(events))
+	}
+
+	expected := []struct {
+		direction MessageDirection
+		messageID string
+		contains  string
+	}{
+		{MessageReceived, "", "<session-id>42</session-id>"},
+		{MessageSent, "", "<capability>urn:ietf:params:netconf:base:1.0</capability>"},
+		{MessageSent, reply.MessageID, "<lock><target><candidate/></target></lock>"},
+		{MessageReceived, reply.MessageID, "<ok/>"},
+	}
+	for i, e := range events {
+		if e.Direction != expected[i].direction || e.MessageID != expected[i].messageID {
+			t.Errorf("event %d: got %s %q, expected %s %q", i, e.Direction, e.MessageID, expected[i].direction, expected[i].messageID)
+		}
+		if !strings.Contains(string(e.Message), expected[i].contains) {
+			t.Errorf("event %d: message %s does not contain %s", i, e.Message, expected[i].contains)
+		}
+		if e.SessionID != 42 || e.Bytes != len(e.Message) {
+			t.Errorf("event %d: got session-id %d and %d bytes", i, e.SessionID, e.Bytes)
+		}
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	tt := []struct {
+		name     string
+		redactor *Redactor
+		message  string
+		expected string
+	}{
+		{
+			name:     "default",
+			redactor: DefaultRedactor,
+			message:  `<rpc><edit-config><config><system><root-authentication><encrypted-password>$6$abc&amp;</encrypted-password></root-authentication></system></config></edit-config></rpc>`,
+			expected: `<rpc><edit-config><config><system><root-authentication><encrypted-password>REDACTED</encrypted-password></root-authentication></system></config></edit-config></rpc>`,
+		},
+		{
+			name:     "nested",
+			redactor: &Redactor{Elements: []string{"secret"}, Replacement: "***"},
+			message:  `<a><secret><key>k</key><value>v</value></secret><b>keep</b><secret/></a>`,
+			expected: `<a><secret>***</secret><b>keep</b><secret/></a>`,
+		},
+		{
+			name:     "paths",
+			redactor: &Redactor{Paths: []string{"snmp/community/name", "/data/key"}},
+			message:  `<data><snmp><community><name>public</name></community></snmp><name>r1</name><key>k</key><x><key>keep</key></x></data>`,
+			expected: `<data><snmp><community><name>REDACTED</name></community></snmp><name>r1</name><key>REDACTED</key><x><key>keep</key></x></data>`,
+		},
+		{
+			name:     "text",
+			redactor: DefaultRedactor,
+			message: `<configuration-text>system {
+    root-authentication {
+        encrypted-password "$6$abc"; ## SECRET-DATA
+    }
+    login {
+        user admin {
+            authentication {
+                encrypted-password &quot;$6$def&quot;;
+            }
+        }
+    }
+    host-name r1;
+}
+snmp {
+    community "public"; ## SECRET-DATA
+}</configuration-text>`,
+			expected: `<configuration-text>system {
+    root-authentication {
+        encrypted-password "REDACTED"; ## SECRET-DATA
+    }
+    login {
+        user admin {
+            authentication {
+                encrypted-password &quot;REDACTED&quot;;
+            }
+        }
+    }
+    host-name r1;
+}
+snmp {
+    community "REDACTED"; ## SECRET-DATA
+}</configuration-text>`,
+		},
+		{
+			name:     "set",
+			redactor: &Redactor{Statements: []string{"secret", "authentication-key"}, Replacement: "***"},
+			message: `<configuration-set>set system radius-server 10.0.0.1 secret "$9$xyz"
+set protocols ospf area 0 interface ge-0/0/0 authentication md5 1 key abc
+set protocols bgp authentication-key s3cret
+set system host-name secret-router</configuration-set><secret>keep</secret>`,
+			expected: `<configuration-set>set system radius-server 10.0.0.1 secret "***"
+set protocols ospf area 0 interface ge-0/0/0 authentication md5 1 key abc
+set protocols bgp authentication-key ***
+set system host-name secret-router</configuration-set><secret>keep</secret>`,
+		},
+		{
+			name:     "malformed",
+			redactor: DefaultRedactor,
+			message:  `<password>secret`,
+			expected: `<password>secret`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := string(tc.redactor.Redact([]byte(tc.message)))
+			if got != tc.expected {
+				t.Errorf("got %s, expected %s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRedactHook(t *testing.T) {
+	var got *MessageEvent
+	hook := RedactHook(nil, MessageHookFunc(func(e *MessageEvent) { got = e }))
+
+	msg := []byte(`<password>secret</password>`)
+	hook.Message(&MessageEvent{Message: msg, Bytes: len(msg)})
+
+	if string(got.Message) != `<password>REDACTED</password>` {
+		t.Errorf("message not redacted: %s", got.Message)
+	}
+	if got.Bytes != len(msg) {
+		t.Errorf("got %d bytes, expected %d", got.Bytes, len(msg))
+	}
+	if string(msg) != `<password>secret</password>` {
+		t.Errorf("original message modified: %s", msg)
+	}
+}
+
+func TestHookTransport(t *testing.T) {
+	reply := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="%s"><ok/></rpc-reply>`
+	r := &messageReader{messages: []string{testServerHello + msgSeperator}}
+	trans := &transportTest{}
+	trans.ReadWriteCloser = newNilCloser(r, new(bytes.Buffer))
+
+	var events []MessageEvent
+	ht := NewHookTransport(trans, MessageHookFunc(func(e *MessageEvent) { events = append(events, *e) }))
+	s := NewSession(ht)
+	if s.SessionID != 42 {
+		t.Fatalf("got session-id %d, expected 42", s.SessionID)
+	}
+
+	rpc := NewRPCMessage([]RPCMethod{MethodLock("candidate")})
+	r.messages = append(r.messages, fmt.Sprintf(reply, rpc.MessageID)+msgSeperator)
+	if _, err := s.exec(rpc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		direction MessageDirection
+		messageID string
+		contains  string
+	}{
+		{MessageReceived, "", "<session-id>42</session-id>"},
+		{MessageSent, "", "<capability>urn:ietf:params:netconf:base:1.0</capability>"},
+		{MessageSent, rpc.MessageID, "<lock><target><candidate/></target></lock>"},
+		{MessageReceived, rpc.MessageID, "<ok/>"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("got %d events, expected %d", len(events), len(expected))
+	}
+	for i, e := range events {
+		if e.Direction != expected[i].direction || e.MessageID != expected[i].messageID {
+			t.Errorf("event %d: got %s %q, expected %s %q", i, e.Direction, e.MessageID, expected[i].direction, expected[i].messageID)
+		}
+		if !strings.Contains(string(e.Message), expected[i].contains) {
+			t.Errorf("event %d: message %s does not contain %s", i, e.Message, expected[i].contains)
+		}
+		if e.SessionID != 42 || e.Bytes != len(e.Message) {
+			t.Errorf("event %d: got session-id %d and %d bytes", i, e.SessionID, e.Bytes)
+		}
+	}
+}
+
+func TestRedactorStatementRegexp(t *testing.T) {
+	r := &Redactor{Statements: []string{"secret", "a.b(c"}}
+	msg := []byte(`<configuration-set>set system radius-server 10.0.0.1 secret s3cret</configuration-set>`)
+
+	first := r.Redact(msg)
+	statement := r.statementRegexp()
+	if statement == nil {
+		t.Fatalf("statement regexp not compiled")
+	}
+	if second := r.Redact(msg); string(second) != string(first) || r.statementRegexp() != statement {
+		t.Errorf("statement regexp compiled again")
+	}
+	if !strings.Contains(string(first), "secret REDACTED") {
+		t.Errorf("statement not redacted: %s", first)
+	}
+
+	if (&Redactor{}).statementRegexp() != nil {
+		t.Errorf("unexpected statement regexp without statements")
+	}
+}
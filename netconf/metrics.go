@@ -0,0 +1,71 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"time"
+)
+
+// Metrics receives the measurements of sessions, to be exposed as counters
+// and histograms by adapters for Prometheus, OpenTelemetry or the like.
+// Methods are called synchronously, possibly from several sessions at once.
+type Metrics interface {
+	// SessionOpened is called once the hello exchange succeeded, with the
+	// framing version negotiated, v1.0 or v1.1
+	SessionOpened(version string)
+	// SessionFailed is called when the hello exchange fails, or the
+	// connection fails in the Dial functions and when opening a session on
+	// an existing connection
+	SessionFailed(err error)
+	// RPC is called for every rpc executed, with the name of its first
+	// operation, the time until its reply was received, the size of the
+	// reply and the error returned by Exec, if any
+	RPC(operation string, duration time.Duration, replyBytes int, err error)
+	// RPCError is called for every rpc-error of a reply, warnings included,
+	// with its error-tag.  The Junos xnm:error and xnm:warning elements are
+	// reported with the xnm-error and xnm-warning tags.
+	RPCError(operation string, tag string)
+	// Bytes is called for every rpc sent and reply received, with the size
+	// of the message without framing
+	Bytes(direction MessageDirection, version string, n int)
+}
+
+// DefaultMetrics sets the metrics of new sessions, none when nil.
+var DefaultMetrics Metrics
+
+// sessionFailed reports err to DefaultMetrics
+func sessionFailed(err error) {
+	if DefaultMetrics != nil {
+		DefaultMetrics.SessionFailed(err)
+	}
+}
+
+// observe reports an rpc executed by s to its metrics
+func (s *Session) observe(methods []RPCMethod, request []byte, rawXML []byte, reply *RPCReply, duration time.Duration, err error) {
+	m := s.Metrics
+	if m == nil {
+		return
+	}
+
+	operation := operationName(methods)
+
+	if request != nil {
+		m.Bytes(MessageSent, s.version, len(request))
+	}
+	if rawXML != nil {
+		m.Bytes(MessageReceived, s.version, len(rawXML))
+	}
+	m.RPC(operation, duration, len(rawXML), err)
+	if reply != nil {
+		for _, e := range reply.Errors {
+			m.RPCError(operation, e.Tag)
+		}
+		for _, e := range reply.JunosErrors {
+			m.RPCError(operation, "xnm-"+e.Severity)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/crypto/ssh"
+)
+
+// metricsTest records the calls to its methods
+type metricsTest struct {
+	calls []string
+}
+
+func (m *metricsTest) SessionOpened(version string) {
+	m.calls = append(m.calls, fmt.Sprintf("opened %s", version))
+}
+
+func (m *metricsTest) SessionFailed(err error) {
+	m.calls = append(m.calls, fmt.Sprintf("failed %v", err))
+}
+
+func (m *metricsTest) RPC(operation string, duration time.Duration, replyBytes int, err error) {
+	m.calls = append(m.calls, fmt.Sprintf("rpc %s %d %v", operation, replyBytes, err))
+}
+
+func (m *metricsTest) RPCError(operation string, tag string) {
+	m.calls = append(m.calls, fmt.Sprintf("rpc-error %s %s", operation, tag))
+}
+
+func (m *metricsTest) Bytes(direction MessageDirection, version string, n int) {
+	m.calls = append(m.calls, fmt.Sprintf("bytes %s %s %d", direction, version, n))
+}
+
+func TestSessionMetrics(t *testing.T) {
+	const errorReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><rpc-error>
+<error-type>protocol</error-type>
+<error-tag>lock-denied</error-tag>
+<error-severity>error</error-severity>
+</rpc-error></rpc-reply>`
+
+	m := new(metricsTest)
+	defer func(metrics Metrics) { DefaultMetrics = metrics }(DefaultMetrics)
+	DefaultMetrics = m
+
+	s, out := newSessionTest(testOkReply, errorReply)
+	out.Reset()
+
+	if _, err := s.Exec(MethodLock("candidate")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sent := []int{out.Len() - len(msgSeperator)}
+	out.Reset()
+	_, err := s.Exec(MethodLock("candidate"))
+	if err == nil {
+		t.Fatalf("expected rpc-error")
+	}
+	sent = append(sent, out.Len()-len(msgSeperator))
+	out.Reset()
+	if _, err := s.Exec(MethodUnlock("candidate")); err != io.EOF {
+		t.Fatalf("got error %v, expected %v", err, io.EOF)
+	}
+	sent = append(sent, out.Len()-len(msgSeperator))
+
+	expected := []string{
+		"opened v1.0",
+		fmt.Sprintf("bytes sent v1.0 %d", sent[0]),
+		fmt.Sprintf("bytes received v1.0 %d", len(testOkReply)),
+		fmt.Sprintf("rpc lock %d <nil>", len(testOkReply)),
+		fmt.Sprintf("bytes sent v1.0 %d", sent[1]),
+		fmt.Sprintf("bytes received v1.0 %d", len(errorReply)),
+		fmt.Sprintf("rpc lock %d %v", len(errorReply), err),
+		"rpc-error lock lock-denied",
+		fmt.Sprintf("bytes sent v1.0 %d", sent[2]),
+		"rpc unlock 0 EOF",
+	}
+	if diff := cmp.Diff(expected, m.calls); diff != "" {
+		t.Errorf("metrics mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSessionMetricsHelloFailure(t *testing.T) {
+	m := new(metricsTest)
+	defer func(metrics Metrics) { DefaultMetrics = metrics }(DefaultMetrics)
+	DefaultMetrics = m
+
+	trans := &transportTest{}
+	trans.ReadWriteCloser = newNilCloser(&messageReader{}, ioutil.Discard)
+	NewSession(trans)
+
+	if diff := cmp.Diff([]string{"failed EOF"}, m.calls); diff != "" {
+		t.Errorf("metrics mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSessionMetricsJunosErrors(t *testing.T) {
+	const warningReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/15.1R1/junos">
+<load-configuration-results>
+<xnm:warning xmlns="http://xml.juniper.net/xnm/1.1/xnm" xmlns:xnm="http://xml.juniper.net/xnm/1.1/xnm"><message>statement not found</message></xnm:warning>
+<ok/>
+</load-configuration-results>
+</rpc-reply>`
+
+	m := new(metricsTest)
+	defer func(metrics Metrics) { DefaultMetrics = metrics }(DefaultMetrics)
+	DefaultMetrics = m
+
+	s, _ := newSessionTest(warningReply)
+	m.calls = nil
+	if _, err := s.Exec(MethodLock("candidate")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if m.calls[len(m.calls)-1] != "rpc-error lock xnm-warning" {
+		t.Errorf("xnm:warning not reported: %v", m.calls)
+	}
+}
+
+func TestDialMetricsFailure(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	// Nothing listens on the address once closed
+	addr := l.Addr().String()
+	l.Close()
+
+	config := SSHConfigPassword("user", "pass")
+	tt := []struct {
+		name string
+		dial func() error
+	}{
+		{"DialSSH", func() error { _, err := DialSSH(addr, config); return err }},
+		{"DialSSHTimeout", func() error { _, err := DialSSHTimeout(addr, config, time.Second); return err }},
+		{"DialSSHJump", func() error {
+			_, err := DialSSHJump("device", config, SSHJumpHost{Target: addr, Config: config})
+			return err
+		}},
+		{"DialSSHAgent", func() error {
+			conn, _ := net.Pipe()
+			_, err := DialSSHAgent(addr, config, &SSHAgent{conn: conn})
+			return err
+		}},
+		{"DialSSHConnection", func() error { _, err := DialSSHConnection(addr, config); return err }},
+		{"SSHConfigFile.Dial", func() error {
+			c, err := ParseSSHConfig(strings.NewReader("Host device\n  ProxyCommand exit 1\n"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, err = c.Dial("device", ssh.Password("pass"))
+			return err
+		}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			m := new(metricsTest)
+			defer func(metrics Metrics) { DefaultMetrics = metrics }(DefaultMetrics)
+			DefaultMetrics = m
+
+			err := tc.dial()
+			if err == nil {
+				t.Fatalf("expected dial error")
+			}
+			if diff := cmp.Diff([]string{fmt.Sprintf("failed %v", err)}, m.calls); diff != "" {
+				t.Errorf("metrics mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
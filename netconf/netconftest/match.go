@@ -0,0 +1,259 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconftest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Request is a rpc received by the Server.
+type Request struct {
+	MessageID string
+	// Name is the name of the operation, the first element of the rpc
+	Name string
+	// Body is the content of the rpc element
+	Body string
+	// Raw is the whole rpc message
+	Raw string
+
+	root *node
+}
+
+// node is an element of a parsed request
+type node struct {
+	name     string
+	attrs    []xml.Attr
+	text     string
+	children []*node
+}
+
+// parseRequest parses a rpc message
+func parseRequest(raw []byte) (*Request, error) {
+	var rpc struct {
+		XMLName   xml.Name
+		MessageID string `xml:"message-id,attr"`
+		Body      string `xml:",innerxml"`
+	}
+	if err := xml.Unmarshal(raw, &rpc); err != nil {
+		return nil, err
+	}
+	if rpc.XMLName.Local != "rpc" {
+		return nil, fmt.Errorf("netconftest: unexpected message <%s>", rpc.XMLName.Local)
+	}
+
+	root, err := parseNodes(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &Request{MessageID: rpc.MessageID, Body: rpc.Body, Raw: string(raw), root: root}
+	if len(root.children) > 0 && len(root.children[0].children) > 0 {
+		req.Name = root.children[0].children[0].name
+	}
+	return req, nil
+}
+
+// parseNodes returns a document node holding the elements of raw
+func parseNodes(raw []byte) (*node, error) {
+	doc := &node{}
+	stack := []*node{doc}
+
+	d := xml.NewDecoder(bytes.NewReader(raw))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return doc, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			n := &node{name: tok.Name.Local, attrs: tok.Attr}
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			parent.text += string(tok)
+		}
+	}
+}
+
+// Matcher selects requests.
+type Matcher interface {
+	Match(req *Request) bool
+}
+
+// MatcherFunc is an adapter allowing a function to be used as a Matcher.
+type MatcherFunc func(req *Request) bool
+
+// Match calls f(req)
+func (f MatcherFunc) Match(req *Request) bool {
+	return f(req)
+}
+
+// MatchAny matches every request.
+func MatchAny() Matcher {
+	return describedMatcher{"any", func(req *Request) bool { return true }}
+}
+
+// MatchName matches the requests of the named operation, e.g. get-config.
+func MatchName(name string) Matcher {
+	return describedMatcher{"name " + name, func(req *Request) bool { return req.Name == name }}
+}
+
+// MatchRegexp matches the requests whose body matches the regular
+// expression.
+func MatchRegexp(expr string) Matcher {
+	re := regexp.MustCompile(expr)
+	return describedMatcher{"regexp " + expr, func(req *Request) bool { return re.MatchString(req.Body) }}
+}
+
+// MatchXPath matches the requests holding an element selected by path, an
+// XPath subset evaluated from the rpc element.  Steps are element names or *,
+// separated by / for children or // for descendants, optionally followed by
+// [@attr='value'] or [child='value'] predicates.  Paths starting with /
+// are evaluated from the document, so start with /rpc.
+//
+//	get-config/source/running
+//	//interface[name='ge-0/0/0']
+//	/rpc/edit-config//*[@operation='delete']
+func MatchXPath(path string) Matcher {
+	steps, err := parseXPath(path)
+	if err != nil {
+		panic(err)
+	}
+	return describedMatcher{"xpath " + path, func(req *Request) bool {
+		context := []*node{req.root}
+		if !strings.HasPrefix(path, "/") && len(req.root.children) > 0 {
+			context = req.root.children[:1]
+		}
+		for _, step := range steps {
+			context = step.eval(context)
+		}
+		return len(context) > 0
+	}}
+}
+
+type describedMatcher struct {
+	desc  string
+	match func(req *Request) bool
+}
+
+func (m describedMatcher) Match(req *Request) bool {
+	return m.match(req)
+}
+
+func (m describedMatcher) String() string {
+	return m.desc
+}
+
+// xpathStep is a location step of a MatchXPath path
+type xpathStep struct {
+	descendant bool
+	name       string
+	predicates []xpathPredicate
+}
+
+type xpathPredicate struct {
+	attr  bool
+	name  string
+	value string
+}
+
+var xpathStepRegexp = regexp.MustCompile(`^(//?)([\w.:*-]+)((?:\[[^\]]*\])*)`)
+var xpathPredicateRegexp = regexp.MustCompile(`\[\s*(@?)([\w.:-]+)\s*=\s*(?:'([^']*)'|"([^"]*)")\s*\]`)
+
+func parseXPath(path string) ([]xpathStep, error) {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	var steps []xpathStep
+	for rest := path; rest != ""; {
+		m := xpathStepRegexp.FindStringSubmatch(rest)
+		if m == nil {
+			return nil, fmt.Errorf("netconftest: invalid xpath %q at %q", path, rest)
+		}
+		rest = rest[len(m[0]):]
+
+		step := xpathStep{descendant: m[1] == "//", name: m[2]}
+		preds := m[3]
+		for _, p := range xpathPredicateRegexp.FindAllStringSubmatch(preds, -1) {
+			step.predicates = append(step.predicates, xpathPredicate{attr: p[1] == "@", name: p[2], value: p[3] + p[4]})
+			preds = strings.Replace(preds, p[0], "", 1)
+		}
+		if preds != "" {
+			return nil, fmt.Errorf("netconftest: unsupported xpath predicate %q", preds)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// eval returns the nodes selected by the step from the context nodes
+func (s xpathStep) eval(context []*node) []*node {
+	var selected []*node
+	var visit func(n *node)
+	visit = func(n *node) {
+		for _, c := range n.children {
+			if s.matches(c) {
+				selected = append(selected, c)
+			}
+			if s.descendant {
+				visit(c)
+			}
+		}
+	}
+	for _, n := range context {
+		visit(n)
+	}
+	return selected
+}
+
+func (s xpathStep) matches(n *node) bool {
+	if s.name != "*" && localName(s.name) != n.name {
+		return false
+	}
+
+	for _, p := range s.predicates {
+		found := false
+		if p.attr {
+			for _, a := range n.attrs {
+				if a.Name.Local == localName(p.name) && a.Value == p.value {
+					found = true
+				}
+			}
+		} else {
+			for _, c := range n.children {
+				if c.name == localName(p.name) && strings.TrimSpace(c.text) == p.value {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// localName strips the namespace prefix of a name
+func localName(name string) string {
+	if i := strings.LastIndexByte(name, ':'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,269 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconftest
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Juniper/go-netconf/netconf"
+)
+
+// Directions of recorded messages.
+const (
+	DirectionSend    = "send"
+	DirectionReceive = "receive"
+)
+
+// Event is a message of a recorded session, hellos included.
+type Event struct {
+	Time time.Time `json:"time"`
+	// Direction is DirectionSend for the messages sent by the client and
+	// DirectionReceive for the ones it received
+	Direction string `json:"direction"`
+	Message   string `json:"message"`
+}
+
+// Recorder is a netconf.Transport writing every message exchanged by the
+// wrapped Transport as JSON lines of Event.
+type Recorder struct {
+	netconf.Transport
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder creates a Recorder wrapping t and writing to w.
+func NewRecorder(t netconf.Transport, w io.Writer) *Recorder {
+	return &Recorder{Transport: t, enc: json.NewEncoder(w)}
+}
+
+// Send records and sends a message.
+func (r *Recorder) Send(data []byte) error {
+	r.record(DirectionSend, data)
+	return r.Transport.Send(data)
+}
+
+// Receive receives and records a message.
+func (r *Recorder) Receive() ([]byte, error) {
+	data, err := r.Transport.Receive()
+	if err == nil {
+		r.record(DirectionReceive, data)
+	}
+	return data, err
+}
+
+// SendHello sends and records a hello message.
+func (r *Recorder) SendHello(hello *netconf.HelloMessage) error {
+	return sendHello(r, hello)
+}
+
+// ReceiveHello receives and records a hello message.
+func (r *Recorder) ReceiveHello() (*netconf.HelloMessage, error) {
+	return receiveHello(r)
+}
+
+// RecordErr returns the first error writing the recording.  Recording
+// errors do not fail the operations of the transport.
+func (r *Recorder) RecordErr() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Err returns the error marking the wrapped Transport dead, if it reports
+// one, so that ManagedSession and Pool see through the Recorder.
+func (r *Recorder) Err() error {
+	if t, ok := r.Transport.(interface{ Err() error }); ok {
+		return t.Err()
+	}
+	return nil
+}
+
+func (r *Recorder) record(direction string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(Event{Time: time.Now(), Direction: direction, Message: string(data)})
+}
+
+// ReplayMismatchError is returned by a Replay when the client does not send
+// the recorded message.
+type ReplayMismatchError struct {
+	// Index is the position of the event in the recording
+	Index    int
+	Expected string
+	Got      string
+}
+
+// Error generates a string representation of the mismatch
+func (e *ReplayMismatchError) Error() string {
+	return fmt.Sprintf("netconftest: replay event %d: expected %s, got %s", e.Index, e.Expected, e.Got)
+}
+
+// Replay is a netconf.Transport playing back a recording.  Messages sent by
+// the client must match the recorded ones, message-id attributes aside, and
+// received messages get the message-id of the last request.
+type Replay struct {
+	mu        sync.Mutex
+	events    []Event
+	pos       int
+	messageID string
+	closed    bool
+}
+
+// NewReplay creates a Replay of the JSON lines recording read from r.
+func NewReplay(r io.Reader) (*Replay, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("netconftest: invalid recording: %v", err)
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &Replay{events: events}, nil
+}
+
+// LoadReplay creates a Replay of the recording held by file.
+func LoadReplay(file string) (*Replay, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewReplay(f)
+}
+
+var messageIDRegexp = regexp.MustCompile(`message-id="[^"]*"`)
+
+// Send verifies the message is the next one recorded.
+func (r *Replay) Send(data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, err := r.next(DirectionSend)
+	if err != nil {
+		return err
+	}
+
+	got, expected := normalizeMessage(string(data)), normalizeMessage(e.Message)
+	if got != expected {
+		return &ReplayMismatchError{Index: r.pos - 1, Expected: expected, Got: got}
+	}
+
+	if m := messageIDRegexp.FindString(string(data)); m != "" {
+		r.messageID = m
+	}
+	return nil
+}
+
+// Receive returns the next recorded message.
+func (r *Replay) Receive() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, err := r.next(DirectionReceive)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := e.Message
+	if r.messageID != "" {
+		msg = messageIDRegexp.ReplaceAllLiteralString(msg, r.messageID)
+	}
+	return []byte(msg), nil
+}
+
+// SendHello verifies the hello is the next message recorded.
+func (r *Replay) SendHello(hello *netconf.HelloMessage) error {
+	return sendHello(r, hello)
+}
+
+// ReceiveHello returns the recorded hello.
+func (r *Replay) ReceiveHello() (*netconf.HelloMessage, error) {
+	return receiveHello(r)
+}
+
+// SetVersion is a no-op as recorded messages are unframed.
+func (r *Replay) SetVersion(version string) {}
+
+// Close ends the replay, later operations fail with io.EOF.
+func (r *Replay) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+// Remaining returns the number of recorded messages not played yet.
+func (r *Replay) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events) - r.pos
+}
+
+func (r *Replay) next(direction string) (Event, error) {
+	if r.closed || r.pos >= len(r.events) {
+		return Event{}, io.EOF
+	}
+
+	e := r.events[r.pos]
+	if e.Direction != direction {
+		return Event{}, fmt.Errorf("netconftest: replay event %d: expected %s, got %s", r.pos, e.Direction, direction)
+	}
+	r.pos++
+	return e, nil
+}
+
+// normalizeMessage strips what differs between runs of the same client
+func normalizeMessage(msg string) string {
+	return strings.TrimSpace(messageIDRegexp.ReplaceAllLiteralString(msg, `message-id=""`))
+}
+
+// sendHello sends a hello the way netconf.TransportBasicIO does, so wrappers
+// see it as a message
+func sendHello(t netconf.Transport, hello *netconf.HelloMessage) error {
+	val, err := xml.Marshal(hello)
+	if err != nil {
+		return err
+	}
+	return t.Send(append([]byte(xml.Header), val...))
+}
+
+// receiveHello receives a hello the way netconf.TransportBasicIO does
+func receiveHello(t netconf.Transport) (*netconf.HelloMessage, error) {
+	hello := new(netconf.HelloMessage)
+
+	val, err := t.Receive()
+	if err != nil {
+		return hello, err
+	}
+
+	err = xml.Unmarshal(val, hello)
+	return hello, err
+}
@@ -0,0 +1,145 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconftest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Juniper/go-netconf/netconf"
+)
+
+func TestRecordReplay(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.Handle(MatchName("get-config"), ReplyData("<system><host-name>r1</host-name></system>"))
+
+	var recording bytes.Buffer
+	recorder := NewRecorder(srv.Transport(), &recording)
+	s := netconf.NewSession(recorder)
+	reply, err := s.Exec(netconf.MethodGetConfig("running"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.Close()
+	if err := recorder.RecordErr(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Server hello, client hello, rpc and reply
+	if n := strings.Count(recording.String(), "\n"); n != 4 {
+		t.Fatalf("got %d events, expected 4:\n%s", n, recording.String())
+	}
+
+	replay, err := NewReplay(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s = netconf.NewSession(replay)
+	if s.SessionID != 1 {
+		t.Errorf("got session-id %d, expected 1", s.SessionID)
+	}
+
+	replayed, err := s.Exec(netconf.MethodGetConfig("running"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replayed.Data != reply.Data {
+		t.Errorf("got %s, expected %s", replayed.Data, reply.Data)
+	}
+	if !strings.Contains(replayed.RawReply, `message-id="`+replayed.MessageID+`"`) {
+		t.Errorf("reply message-id not rewritten: %s", replayed.RawReply)
+	}
+	if n := replay.Remaining(); n != 0 {
+		t.Errorf("got %d remaining events, expected 0", n)
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.Handle(MatchAny(), ReplyOK())
+
+	var recording bytes.Buffer
+	s := netconf.NewSession(NewRecorder(srv.Transport(), &recording))
+	if _, err := s.Exec(netconf.MethodLock("candidate")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	replay, err := NewReplay(&recording)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s = netconf.NewSession(replay)
+
+	_, err = s.Exec(netconf.MethodLock("running"))
+	mismatch, ok := err.(*ReplayMismatchError)
+	if !ok {
+		t.Fatalf("got error %v, expected *ReplayMismatchError", err)
+	}
+	if mismatch.Index != 2 || !strings.Contains(mismatch.Got, "<running/>") {
+		t.Errorf("unexpected mismatch %v", mismatch)
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+// deadTransport is a transport reporting itself dead
+type deadTransport struct {
+	netconf.Transport
+	err error
+}
+
+func (t deadTransport) Err() error {
+	return t.err
+}
+
+func TestRecorderManagedSession(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.Handle(MatchName("get-config"), ReplyData("<system/>"))
+
+	var recorders []*Recorder
+	m := netconf.NewManagedSession(func(ctx context.Context) (*netconf.Session, error) {
+		r := NewRecorder(srv.Transport(), failingWriter{})
+		recorders = append(recorders, r)
+		return netconf.NewSessionContext(ctx, r), nil
+	})
+	defer m.Close()
+
+	// Failing to record keeps the session
+	for i := 0; i < 2; i++ {
+		if _, err := m.Exec(context.Background(), netconf.MethodGetConfig("running")); err != nil {
+			t.Fatalf("exec %d: unexpected error: %v", i, err)
+		}
+	}
+	if len(recorders) != 1 {
+		t.Fatalf("got %d dials, expected 1", len(recorders))
+	}
+	if err := recorders[0].RecordErr(); err == nil || err.Error() != "disk full" {
+		t.Errorf("got recording error %v, expected disk full", err)
+	}
+	if err := recorders[0].Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// The transport errors are forwarded
+	dead := errors.New("dead")
+	r := NewRecorder(deadTransport{Transport: srv.Transport(), err: dead}, &bytes.Buffer{})
+	if err := r.Err(); err != dead {
+		t.Errorf("got error %v, expected %v", err, dead)
+	}
+}
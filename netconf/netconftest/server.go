@@ -0,0 +1,341 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconftest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Juniper/go-netconf/netconf"
+)
+
+const (
+	msgSeparator     = "]]>]]>"
+	capabilityBase11 = "urn:ietf:params:netconf:base:1.1"
+)
+
+// Responder returns the content of the rpc-reply answering a request.
+type Responder func(req *Request) string
+
+// Reply answers with the given rpc-reply content.
+func Reply(content string) Responder {
+	return func(req *Request) string { return content }
+}
+
+// ReplyOK answers with <ok/>.
+func ReplyOK() Responder {
+	return Reply("<ok/>")
+}
+
+// ReplyData answers with a data element holding data.
+func ReplyData(data string) Responder {
+	return Reply("<data>" + data + "</data>")
+}
+
+// ReplyError answers with a rpc-error.
+func ReplyError(err netconf.RPCError) Responder {
+	var buf bytes.Buffer
+	buf.WriteString("<rpc-error>")
+	for _, field := range []struct{ name, value string }{
+		{"error-type", err.Type},
+		{"error-tag", err.Tag},
+		{"error-severity", err.Severity},
+		{"error-path", err.Path},
+		{"error-message", err.Message},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(&buf, "<%s>", field.name)
+			xml.EscapeText(&buf, []byte(field.value))
+			fmt.Fprintf(&buf, "</%s>", field.name)
+		}
+	}
+	buf.WriteString("</rpc-error>")
+	return Reply(buf.String())
+}
+
+// handler answers the requests matched by m, or drops the connection when
+// respond is nil
+type handler struct {
+	m       Matcher
+	respond Responder
+}
+
+// Server is a scriptable fake NETCONF server.  Requests are answered by the
+// first handler matching them, or with an operation-not-supported rpc-error
+// when none does, and are recorded for assertions.  close-session is always
+// answered with <ok/> before closing the session.
+type Server struct {
+	// Capabilities are sent in the hello, base:1.0 only by default.  Chunked
+	// framing is used when both peers support base:1.1.
+	Capabilities []string
+
+	mu        sync.Mutex
+	handlers  []handler
+	requests  []*Request
+	conns     []net.Conn
+	sessionID int
+}
+
+// NewServer creates a Server without handlers.
+func NewServer() *Server {
+	return &Server{Capabilities: []string{"urn:ietf:params:netconf:base:1.0"}}
+}
+
+// Handle answers the requests matched by m with r.  Handlers are tried in the
+// order they were added.
+func (s *Server) Handle(m Matcher, r Responder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler{m: m, respond: r})
+}
+
+// Disconnect closes the session without reply when a request matched by m is
+// received, simulating a transport failure.
+func (s *Server) Disconnect(m Matcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler{m: m})
+}
+
+// Transport returns a Transport connected to a new session of the server.
+// The server sends its hello first, so the Transport is ready to be passed
+// to netconf.NewSession.
+func (s *Server) Transport() *Transport {
+	t, conn := Pipe()
+
+	s.mu.Lock()
+	s.sessionID++
+	id := s.sessionID
+	s.conns = append(s.conns, conn)
+	s.mu.Unlock()
+
+	go s.serve(conn, id)
+	return t
+}
+
+// Session returns a netconf.Session connected to a new session of the
+// server.
+func (s *Server) Session() *netconf.Session {
+	return netconf.NewSession(s.Transport())
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Request(nil), s.requests...)
+}
+
+// Received returns the requests received so far matched by m.
+func (s *Server) Received(m Matcher) []*Request {
+	var matched []*Request
+	for _, req := range s.Requests() {
+		if m.Match(req) {
+			matched = append(matched, req)
+		}
+	}
+	return matched
+}
+
+// AssertReceived fails the test unless a request matched by m was received.
+func (s *Server) AssertReceived(t testing.TB, m Matcher) {
+	t.Helper()
+	if len(s.Received(m)) == 0 {
+		t.Errorf("no request matching %v received, got:\n%s", m, s.dump())
+	}
+}
+
+// AssertNotReceived fails the test if a request matched by m was received.
+func (s *Server) AssertNotReceived(t testing.TB, m Matcher) {
+	t.Helper()
+	if reqs := s.Received(m); len(reqs) > 0 {
+		t.Errorf("unexpected request matching %v received:\n%s", m, reqs[0].Raw)
+	}
+}
+
+// Close closes the sessions of the server.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = nil
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.Close()
+	}
+	return nil
+}
+
+func (s *Server) dump() string {
+	var buf bytes.Buffer
+	for _, req := range s.Requests() {
+		buf.WriteString(req.Raw)
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// serve runs a session on conn
+func (s *Server) serve(conn net.Conn, id int) {
+	defer conn.Close()
+
+	s.mu.Lock()
+	hello, err := xml.Marshal(&netconf.HelloMessage{Capabilities: s.Capabilities, SessionID: id})
+	s.mu.Unlock()
+	if err != nil {
+		return
+	}
+	if _, err := conn.Write(append([]byte(xml.Header), append(hello, msgSeparator...)...)); err != nil {
+		return
+	}
+
+	r := bufio.NewReader(conn)
+	msg, err := readEOM(r)
+	if err != nil {
+		return
+	}
+	var clientHello netconf.HelloMessage
+	if err := xml.Unmarshal(msg, &clientHello); err != nil {
+		return
+	}
+	chunked := hasCapability(s.Capabilities, capabilityBase11) && hasCapability(clientHello.Capabilities, capabilityBase11)
+
+	for {
+		var msg []byte
+		if chunked {
+			msg, err = readChunked(r)
+		} else {
+			msg, err = readEOM(r)
+		}
+		if err != nil {
+			return
+		}
+
+		req, err := parseRequest(msg)
+		if err != nil {
+			return
+		}
+		content, ok := s.handle(req)
+		if !ok {
+			return
+		}
+
+		var reply bytes.Buffer
+		reply.WriteString(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"`)
+		if req.MessageID != "" {
+			reply.WriteString(` message-id="`)
+			xml.EscapeText(&reply, []byte(req.MessageID))
+			reply.WriteString(`"`)
+		}
+		reply.WriteString(">" + content + "</rpc-reply>")
+
+		// Each message is written at once, see Pipe
+		var framed []byte
+		if chunked {
+			framed = []byte(fmt.Sprintf("\n#%d\n%s\n##\n", reply.Len(), reply.Bytes()))
+		} else {
+			framed = append(reply.Bytes(), msgSeparator...)
+		}
+		if _, err := conn.Write(framed); err != nil {
+			return
+		}
+
+		if req.Name == "close-session" {
+			return
+		}
+	}
+}
+
+// handle records req and returns the reply content, or false when the
+// session must be dropped
+func (s *Server) handle(req *Request) (string, bool) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	handlers := s.handlers
+	s.mu.Unlock()
+
+	for _, h := range handlers {
+		if !h.m.Match(req) {
+			continue
+		}
+		if h.respond == nil {
+			return "", false
+		}
+		return h.respond(req), true
+	}
+
+	if req.Name == "close-session" {
+		return "<ok/>", true
+	}
+	return ReplyError(netconf.RPCError{
+		Type:     "protocol",
+		Tag:      "operation-not-supported",
+		Severity: "error",
+		Message:  "netconftest: no handler for " + req.Name,
+	})(req), true
+}
+
+// readEOM reads a message terminated by the end of message separator
+func readEOM(r *bufio.Reader) ([]byte, error) {
+	var msg []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		msg = append(msg, b)
+		if bytes.HasSuffix(msg, []byte(msgSeparator)) {
+			return bytes.TrimSpace(msg[:len(msg)-len(msgSeparator)]), nil
+		}
+	}
+}
+
+// readChunked reads a message using the RFC 6242 chunked framing
+func readChunked(r *bufio.Reader) ([]byte, error) {
+	var msg []byte
+	for {
+		// Chunk headers are \n#<size>\n, the message ends with \n##\n
+		if _, err := r.Discard(2); err != nil {
+			return nil, err
+		}
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "#" {
+			return msg, nil
+		}
+
+		size, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("netconftest: invalid chunk size %q", line)
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, err
+		}
+		msg = append(msg, chunk...)
+	}
+}
+
+func hasCapability(capabilities []string, capability string) bool {
+	for _, c := range capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
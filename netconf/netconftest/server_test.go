@@ -0,0 +1,136 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconftest
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/Juniper/go-netconf/netconf"
+)
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.Handle(MatchXPath("get-config/source/running"), ReplyData("<system><host-name>r1</host-name></system>"))
+	srv.Handle(MatchName("lock"), ReplyError(netconf.RPCError{
+		Type:     "protocol",
+		Tag:      "lock-denied",
+		Severity: "error",
+		Message:  "Lock failed, lock is already held",
+	}))
+
+	s := srv.Session()
+	defer s.Close()
+
+	if s.SessionID != 1 {
+		t.Errorf("got session-id %d, expected 1", s.SessionID)
+	}
+
+	var config struct {
+		HostName string `xml:"system>host-name"`
+	}
+	if err := s.GetConfig(context.Background(), "running", "", &config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.HostName != "r1" {
+		t.Errorf("got host-name %q, expected r1", config.HostName)
+	}
+
+	_, err := s.Exec(netconf.MethodLock("candidate"))
+	if rpcErr, ok := err.(*netconf.RPCError); !ok || rpcErr.Tag != "lock-denied" {
+		t.Errorf("got error %v, expected lock-denied", err)
+	}
+
+	_, err = s.Exec(netconf.MethodUnlock("candidate"))
+	if rpcErr, ok := err.(*netconf.RPCError); !ok || rpcErr.Tag != "operation-not-supported" {
+		t.Errorf("got error %v, expected operation-not-supported", err)
+	}
+
+	srv.AssertReceived(t, MatchRegexp(`<lock><target><candidate/>`))
+	srv.AssertNotReceived(t, MatchName("commit"))
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("got %d requests, expected 3", n)
+	}
+}
+
+func TestServerChunked(t *testing.T) {
+	srv := NewServer()
+	srv.Capabilities = netconf.DefaultCapabilities
+	defer srv.Close()
+
+	srv.Handle(MatchAny(), ReplyOK())
+
+	s := srv.Session()
+	defer s.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Exec(netconf.MethodGetConfig("running")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := len(srv.Received(MatchName("get-config"))); n != 2 {
+		t.Errorf("got %d requests, expected 2", n)
+	}
+}
+
+func TestServerDisconnect(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.Disconnect(MatchName("commit"))
+
+	s := srv.Session()
+	defer s.Close()
+
+	if _, err := s.Exec(netconf.RawMethod("<commit/>")); err != io.EOF {
+		t.Errorf("got error %v, expected %v", err, io.EOF)
+	}
+}
+
+func TestMatchXPath(t *testing.T) {
+	req, err := parseRequest([]byte(`<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<edit-config>
+<target><candidate/></target>
+<config>
+<configuration>
+<interfaces>
+<interface operation="delete"><name>ge-0/0/0</name></interface>
+</interfaces>
+</configuration>
+</config>
+</edit-config>
+</rpc>`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Name != "edit-config" || req.MessageID != "1" {
+		t.Errorf("got name %q message-id %q", req.Name, req.MessageID)
+	}
+
+	tt := []struct {
+		path     string
+		expected bool
+	}{
+		{"edit-config/target/candidate", true},
+		{"edit-config/target/running", false},
+		{"/rpc/edit-config", true},
+		{"/edit-config", false},
+		{"//interface[name='ge-0/0/0']", true},
+		{"//interface[name='ge-0/0/1']", false},
+		{"edit-config//*[@operation='delete']", true},
+		{"edit-config/*/configuration", true},
+	}
+
+	for _, tc := range tt {
+		if got := MatchXPath(tc.path).Match(req); got != tc.expected {
+			t.Errorf("MatchXPath(%q) = %t, expected %t", tc.path, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package netconftest provides utilities for testing code built on netconf
+sessions: an in-memory Transport, a scriptable fake NETCONF server and the
+recording and replay of real sessions.
+*/
+package netconftest
+
+import (
+	"net"
+
+	"github.com/Juniper/go-netconf/netconf"
+)
+
+// Transport is a netconf.Transport over an in-memory connection.
+type Transport struct {
+	netconf.TransportBasicIO
+}
+
+// Pipe returns a Transport connected to the returned in-memory connection,
+// which plays the server side.  Writes on either end block until read by the
+// other end and a read never spans two writes, so the server must write each
+// message in a single write.
+func Pipe() (*Transport, net.Conn) {
+	client, server := net.Pipe()
+	t := &Transport{}
+	t.ReadWriteCloser = client
+	return t, server
+}
@@ -0,0 +1,305 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrPoolClosed is returned by Get on a closed Pool.
+var ErrPoolClosed = errors.New("netconf: pool closed")
+
+// Pool hands out sessions to devices keyed by target, reusing idle sessions
+// and capping the number of sessions opened to each device.  Sessions are
+// returned to the pool with Put, or Discard when broken.
+type Pool struct {
+	// MaxSessions is the maximum number of sessions, in use or idle, to a
+	// target, 4 by default.  Get waits for a session to be returned when
+	// the limit is reached.
+	MaxSessions int
+	// IdleTimeout closes sessions idle for longer, 5 minutes by default.
+	IdleTimeout time.Duration
+	// HealthCheckAfter is the idle time after which a session is checked by
+	// executing HealthCheck before being handed out, 30 seconds by default.
+	HealthCheckAfter time.Duration
+	// HealthCheck is the RPC checking a session, a get with an empty subtree
+	// filter selecting no data by default.
+	HealthCheck RPCMethod
+
+	dial func(ctx context.Context, target string) (*Session, error)
+
+	mu      sync.Mutex
+	targets map[string]*poolTarget
+	inUse   map[*Session]*poolTarget
+	closed  bool
+	reaper  sync.Once
+	done    chan struct{}
+}
+
+// poolTarget holds the sessions of a target.  Each session, idle or in use,
+// holds a slot.
+type poolTarget struct {
+	slots chan struct{}
+	idle  chan *pooledSession
+}
+
+type pooledSession struct {
+	session  *Session
+	lastUsed time.Time
+}
+
+// NewPool creates a Pool opening sessions with dial.
+func NewPool(dial func(ctx context.Context, target string) (*Session, error)) *Pool {
+	return &Pool{
+		dial:    dial,
+		targets: make(map[string]*poolTarget),
+		inUse:   make(map[*Session]*poolTarget),
+		done:    make(chan struct{}),
+	}
+}
+
+// NewSSHPool creates a Pool opening SSH sessions.  See TransportSSH.Dial for
+// config.
+func NewSSHPool(config *ssh.ClientConfig) *Pool {
+	return NewPool(func(ctx context.Context, target string) (*Session, error) {
+		return DialSSHContext(ctx, target, config)
+	})
+}
+
+// Get returns a healthy session to target, either idle or newly dialed.  The
+// session must be given back with Put or Discard.
+func (p *Pool) Get(ctx context.Context, target string) (*Session, error) {
+	t, err := p.target(target)
+	if err != nil {
+		return nil, err
+	}
+	p.reaper.Do(func() { go p.reap() })
+
+	for {
+		// Prefer idle sessions over dialing
+		var ps *pooledSession
+		select {
+		case ps = <-t.idle:
+		default:
+			select {
+			case ps = <-t.idle:
+			case t.slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-p.done:
+				return nil, ErrPoolClosed
+			}
+		}
+
+		if ps == nil {
+			s, err := p.dial(ctx, target)
+			if err != nil {
+				<-t.slots
+				return nil, err
+			}
+			return p.checkout(t, s)
+		}
+
+		if err := p.check(ctx, ps); err != nil {
+			ps.session.Close()
+			<-t.slots
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		return p.checkout(t, ps.session)
+	}
+}
+
+// Put returns a session obtained with Get to the pool.
+func (p *Pool) Put(s *Session) {
+	p.mu.Lock()
+	t, ok := p.inUse[s]
+	delete(p.inUse, s)
+	if ok && !p.closed && transportErr(s.Transport) == nil {
+		// Never blocks as the session holds a slot
+		t.idle <- &pooledSession{session: s, lastUsed: time.Now()}
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+
+	if ok {
+		s.Close()
+		<-t.slots
+	}
+}
+
+// Discard closes a session obtained with Get, such as one which failed,
+// freeing its slot.
+func (p *Pool) Discard(s *Session) {
+	p.mu.Lock()
+	t, ok := p.inUse[s]
+	delete(p.inUse, s)
+	p.mu.Unlock()
+
+	s.Close()
+	if ok {
+		<-t.slots
+	}
+}
+
+// Exec executes an RPC method or methods on a session to target, discarding
+// the session when the transport fails.
+func (p *Pool) Exec(ctx context.Context, target string, methods ...RPCMethod) (*RPCReply, error) {
+	s, err := p.Get(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := s.ExecContext(ctx, methods...)
+	if err != nil && (IsTransportError(err) || ctx.Err() != nil) {
+		p.Discard(s)
+		return nil, err
+	}
+	p.Put(s)
+	return reply, err
+}
+
+// Close closes the idle sessions and makes Get fail.  Sessions in use are
+// closed when given back.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	targets := p.targets
+	p.mu.Unlock()
+
+	for _, t := range targets {
+		p.evict(t, func(*pooledSession) bool { return true })
+	}
+	return nil
+}
+
+func (p *Pool) target(target string) (*poolTarget, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+	t, ok := p.targets[target]
+	if !ok {
+		max := p.MaxSessions
+		if max <= 0 {
+			max = 4
+		}
+		t = &poolTarget{slots: make(chan struct{}, max), idle: make(chan *pooledSession, max)}
+		p.targets[target] = t
+	}
+	return t, nil
+}
+
+func (p *Pool) checkout(t *poolTarget, s *Session) (*Session, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		s.Close()
+		<-t.slots
+		return nil, ErrPoolClosed
+	}
+	p.inUse[s] = t
+	return s, nil
+}
+
+// check verifies an idle session before handing it out
+func (p *Pool) check(ctx context.Context, ps *pooledSession) error {
+	if err := transportErr(ps.session.Transport); err != nil {
+		return err
+	}
+
+	idle := time.Since(ps.lastUsed)
+	if idle > p.idleTimeout() {
+		return fmt.Errorf("netconf: session idle for %s", idle)
+	}
+
+	after := p.HealthCheckAfter
+	if after <= 0 {
+		after = 30 * time.Second
+	}
+	if idle < after {
+		return nil
+	}
+
+	method := p.HealthCheck
+	if method == nil {
+		method = MethodGet("subtree", "")
+	}
+	_, err := ps.session.ExecContext(ctx, method)
+	return err
+}
+
+func (p *Pool) idleTimeout() time.Duration {
+	if p.IdleTimeout <= 0 {
+		return 5 * time.Minute
+	}
+	return p.IdleTimeout
+}
+
+// reap periodically closes the sessions idle for longer than IdleTimeout
+func (p *Pool) reap() {
+	ticker := time.NewTicker(p.idleTimeout() / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		targets := make([]*poolTarget, 0, len(p.targets))
+		for _, t := range p.targets {
+			targets = append(targets, t)
+		}
+		p.mu.Unlock()
+
+		for _, t := range targets {
+			p.evict(t, func(ps *pooledSession) bool {
+				return time.Since(ps.lastUsed) > p.idleTimeout() || transportErr(ps.session.Transport) != nil
+			})
+		}
+	}
+}
+
+// evict closes the idle sessions of t matching the predicate, keeping the
+// others idle
+func (p *Pool) evict(t *poolTarget, evict func(*pooledSession) bool) {
+	for n := len(t.idle); n > 0; n-- {
+		var ps *pooledSession
+		select {
+		case ps = <-t.idle:
+		default:
+			return
+		}
+
+		if !evict(ps) {
+			t.idle <- ps
+			continue
+		}
+		ps.session.Close()
+		<-t.slots
+	}
+}
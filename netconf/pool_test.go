@@ -0,0 +1,213 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// poolTest dials test sessions receiving the given number of <ok/> replies
+type poolTest struct {
+	mu      sync.Mutex
+	replies int
+	dials   map[string]int
+}
+
+func (pt *poolTest) dial(ctx context.Context, target string) (*Session, error) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	if pt.dials == nil {
+		pt.dials = make(map[string]int)
+	}
+	pt.dials[target]++
+
+	replies := make([]string, pt.replies)
+	for i := range replies {
+		replies[i] = testOkReply
+	}
+	s, _ := newSessionTest(replies...)
+	return s, nil
+}
+
+func (pt *poolTest) count(target string) int {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	return pt.dials[target]
+}
+
+func TestPoolReuse(t *testing.T) {
+	pt := &poolTest{}
+	p := NewPool(pt.dial)
+	defer p.Close()
+
+	ctx := context.Background()
+	s1, err := p.Get(ctx, "r1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Put(s1)
+
+	s2, err := p.Get(ctx, "r1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s2 != s1 {
+		t.Errorf("idle session not reused")
+	}
+	if _, err := p.Get(ctx, "r2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := pt.count("r1"); n != 1 {
+		t.Errorf("got %d dials to r1, expected 1", n)
+	}
+	if n := pt.count("r2"); n != 1 {
+		t.Errorf("got %d dials to r2, expected 1", n)
+	}
+}
+
+func TestPoolMaxSessions(t *testing.T) {
+	pt := &poolTest{}
+	p := NewPool(pt.dial)
+	p.MaxSessions = 1
+	defer p.Close()
+
+	s1, err := p.Get(context.Background(), "r1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Get(ctx, "r1"); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+
+	// Waiters get the session given back
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p.Put(s1)
+	}()
+	s2, err := p.Get(context.Background(), "r1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s2 != s1 {
+		t.Errorf("session given back not reused")
+	}
+
+	// Discarding frees the slot for a new session
+	p.Discard(s2)
+	if _, err := p.Get(context.Background(), "r1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := pt.count("r1"); n != 2 {
+		t.Errorf("got %d dials, expected 2", n)
+	}
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	// Sessions answer a single health check, then fail
+	pt := &poolTest{replies: 1}
+	p := NewPool(pt.dial)
+	p.HealthCheckAfter = time.Nanosecond
+	defer p.Close()
+
+	ctx := context.Background()
+	s1, _ := p.Get(ctx, "r1")
+	p.Put(s1)
+
+	s2, err := p.Get(ctx, "r1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s2 != s1 {
+		t.Errorf("healthy session not reused")
+	}
+	p.Put(s2)
+
+	s3, err := p.Get(ctx, "r1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s3 == s1 {
+		t.Errorf("broken session reused")
+	}
+	if n := pt.count("r1"); n != 2 {
+		t.Errorf("got %d dials, expected 2", n)
+	}
+}
+
+func TestPoolIdleTimeout(t *testing.T) {
+	pt := &poolTest{}
+	p := NewPool(pt.dial)
+	p.IdleTimeout = 10 * time.Millisecond
+	defer p.Close()
+
+	s, _ := p.Get(context.Background(), "r1")
+	p.Put(s)
+
+	time.Sleep(50 * time.Millisecond)
+	p.mu.Lock()
+	idle := len(p.targets["r1"].idle)
+	p.mu.Unlock()
+	if idle != 0 {
+		t.Errorf("got %d idle sessions, expected 0", idle)
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	pt := &poolTest{}
+	p := NewPool(pt.dial)
+	p.MaxSessions = 1
+
+	s, _ := p.Get(context.Background(), "r1")
+
+	// Waiters fail when the pool is closed
+	errs := make(chan error, 1)
+	go func() {
+		_, err := p.Get(context.Background(), "r1")
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	p.Close()
+
+	if err := <-errs; err != ErrPoolClosed {
+		t.Errorf("got error %v, expected %v", err, ErrPoolClosed)
+	}
+	p.Put(s)
+	if _, err := p.Get(context.Background(), "r1"); err != ErrPoolClosed {
+		t.Errorf("got error %v, expected %v", err, ErrPoolClosed)
+	}
+}
+
+func TestSSHPool(t *testing.T) {
+	server := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer server.Close()
+
+	p := NewSSHPool(SSHConfigPassword("test", ""))
+	defer p.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.Exec(context.Background(), server.Addr(), MethodGetConfig("running")); err != nil {
+			t.Fatalf("exec %d: unexpected error: %v", i, err)
+		}
+	}
+
+	p.mu.Lock()
+	idle := len(p.targets[server.Addr()].idle)
+	p.mu.Unlock()
+	if idle != 1 {
+		t.Errorf("got %d idle sessions, expected 1", idle)
+	}
+}
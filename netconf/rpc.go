@@ -12,6 +12,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"strings"
 )
 
 const (
@@ -61,12 +62,24 @@ func (m *RPCMessage) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 
 // RPCReply defines a reply to a RPC request
 type RPCReply struct {
-	XMLName   xml.Name   `xml:"rpc-reply"`
-	Errors    []RPCError `xml:"rpc-error,omitempty"`
-	Data      string     `xml:",innerxml"`
-	Ok        bool       `xml:",omitempty"`
-	RawReply  string     `xml:"-"`
-	MessageID string     `xml:"-"`
+	XMLName xml.Name `xml:"rpc-reply"`
+	// Errors holds the rpc-error elements of the reply, including the ones
+	// Junos nests in result elements such as commit-results
+	Errors []RPCError `xml:"-"`
+	// JunosErrors holds the Junos xnm:error and xnm:warning elements of
+	// the reply
+	JunosErrors []XNMError `xml:"-"`
+	// JunosAttrs holds the junos: attributes of the rpc-reply element and
+	// of its children by local name, such as "style" or "commit-seconds".
+	// The first value of an attribute wins.
+	JunosAttrs map[string]string `xml:"-"`
+	// Style is the junos:style annotation of the reply, e.g. "brief" or
+	// "terse", naming the format Junos rendered the output in
+	Style     string `xml:"-"`
+	Data      string `xml:",innerxml"`
+	Ok        bool   `xml:",omitempty"`
+	RawReply  string `xml:"-"`
+	MessageID string `xml:"-"`
 }
 
 func newRPCReply(rawXML []byte, ErrOnWarning bool, messageID string) (*RPCReply, error) {
@@ -77,6 +90,10 @@ func newRPCReply(rawXML []byte, ErrOnWarning bool, messageID string) (*RPCReply,
 		return nil, err
 	}
 
+	if err := reply.decodeJunos(rawXML); err != nil {
+		return nil, err
+	}
+
 	// will return a valid reply so setting Requests message id
 	reply.MessageID = messageID
 
@@ -88,9 +105,122 @@ func newRPCReply(rawXML []byte, ErrOnWarning bool, messageID string) (*RPCReply,
 		}
 	}
 
+	for _, xnmErr := range reply.JunosErrors {
+		if xnmErr.Severity == "error" || ErrOnWarning {
+			return reply, &xnmErr
+		}
+	}
+
 	return reply, nil
 }
 
+// Unmarshal decodes the data of the reply into v, which describes the
+// children of the data element.  Replies without a data element, such as the
+// ones Junos returns for its own RPCs, are decoded from the rpc-reply element
+// instead so v describes its children.  Since the element decoded into v is
+// either data or rpc-reply, v should not restrict its name with an XMLName
+// field.
+func (r *RPCReply) Unmarshal(v interface{}) error {
+	d := xml.NewDecoder(strings.NewReader(r.RawReply))
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if depth == 1 && tok.Name.Local == "data" {
+				return d.DecodeElement(v, &tok)
+			}
+			if depth == 1 {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	return xml.Unmarshal([]byte(r.RawReply), v)
+}
+
+// decodeJunos collects the rpc-error, xnm:error and xnm:warning elements
+// found anywhere in the reply, and the junos: attributes of the rpc-reply
+// element and its children.
+func (r *RPCReply) decodeJunos(rawXML []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(rawXML))
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			r.Style = r.JunosAttrs["style"]
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, ok := tok.(xml.EndElement); ok {
+			depth--
+			continue
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case start.Name.Local == "rpc-error" && !strings.HasPrefix(start.Name.Space, xnmNamespace):
+			var rpcErr RPCError
+			if err := d.DecodeElement(&rpcErr, &start); err != nil {
+				return err
+			}
+			r.Errors = append(r.Errors, rpcErr)
+		case isXNMError(start):
+			xnmErr := XNMError{Severity: start.Name.Local, JunosAttrs: junosAttrs(start, nil)}
+			if err := d.DecodeElement(&xnmErr, &start); err != nil {
+				return err
+			}
+			r.JunosErrors = append(r.JunosErrors, xnmErr)
+		default:
+			if depth < 2 {
+				r.JunosAttrs = junosAttrs(start, r.JunosAttrs)
+			}
+			depth++
+		}
+	}
+}
+
+// junosNamespace is the namespace prefix of the Junos junos: attributes,
+// followed by the software version
+const junosNamespace = "http://xml.juniper.net/junos/"
+
+// junosAttrs adds the junos: attributes of start missing from attrs, by
+// local name.  Attributes using an undeclared junos prefix are recognized
+// too.
+func junosAttrs(start xml.StartElement, attrs map[string]string) map[string]string {
+	for _, a := range start.Attr {
+		if a.Name.Space != "junos" && !strings.HasPrefix(a.Name.Space, junosNamespace) {
+			continue
+		}
+		if attrs == nil {
+			attrs = map[string]string{}
+		}
+		if _, ok := attrs[a.Name.Local]; !ok {
+			attrs[a.Name.Local] = a.Value
+		}
+	}
+	return attrs
+}
+
 // RPCError defines an error reply to a RPC request
 type RPCError struct {
 	Type     string `xml:"error-type"`
@@ -106,6 +236,37 @@ func (re *RPCError) Error() string {
 	return fmt.Sprintf("netconf rpc [%s] '%s'", re.Severity, re.Message)
 }
 
+// xnmNamespace is the namespace prefix of the Junos xnm:error and
+// xnm:warning elements, followed by the API version
+const xnmNamespace = "http://xml.juniper.net/xnm/"
+
+// XNMError defines a Junos xnm:error or xnm:warning reply to a RPC request
+type XNMError struct {
+	// Severity is "error" or "warning" depending on the element
+	Severity     string `xml:"-"`
+	SourceDaemon string `xml:"source-daemon"`
+	Filename     string `xml:"filename"`
+	LineNumber   int    `xml:"line-number"`
+	Column       int    `xml:"column"`
+	Token        string `xml:"token"`
+	EditPath     string `xml:"edit-path"`
+	Statement    string `xml:"statement"`
+	Message      string `xml:"message"`
+	// JunosAttrs holds the junos: attributes of the element by local name
+	JunosAttrs map[string]string `xml:"-"`
+}
+
+// Error generates a string representation of the provided Junos error
+func (xe *XNMError) Error() string {
+	return fmt.Sprintf("netconf junos [%s] '%s'", xe.Severity, strings.TrimSpace(xe.Message))
+}
+
+// isXNMError reports whether start is an xnm:error or xnm:warning element
+func isXNMError(start xml.StartElement) bool {
+	return strings.HasPrefix(start.Name.Space, xnmNamespace) &&
+		(start.Name.Local == "error" || start.Name.Local == "warning")
+}
+
 // RPCMethod defines the interface for creating an RPC method.
 type RPCMethod interface {
 	MarshalMethod() string
@@ -134,6 +295,13 @@ func MethodGetConfig(source string) RawMethod {
 	return RawMethod(fmt.Sprintf("<get-config><source><%s/></source></get-config>", source))
 }
 
+// MethodGetConfigFilter files a NETCONF get-config source request with the
+// remote host, retrieving the data matching the filter of type filterType,
+// e.g. subtree.
+func MethodGetConfigFilter(source string, filterType string, dataXml string) RawMethod {
+	return RawMethod(fmt.Sprintf("<get-config><source><%s/></source><filter type=\"%s\">%s</filter></get-config>", source, filterType, dataXml))
+}
+
 // MethodGet files a NETCONF get source request with the remote host
 func MethodGet(filterType string, dataXml string) RawMethod {
 	return RawMethod(fmt.Sprintf("<get><filter type=\"%s\">%s</filter></get>", filterType, dataXml))
@@ -144,6 +312,21 @@ func MethodEditConfig(database string, dataXml string) RawMethod {
 	return RawMethod(fmt.Sprintf(editConfigXml, database, dataXml))
 }
 
+// MethodCommit files a NETCONF commit request with the remote host
+func MethodCommit() RawMethod {
+	return RawMethod("<commit/>")
+}
+
+// MethodCreateSubscription files a RFC 5277 create-subscription request with
+// the remote host for the given event stream, the default NETCONF stream when
+// empty.
+func MethodCreateSubscription(stream string) RawMethod {
+	if stream == "" {
+		return RawMethod(`<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"/>`)
+	}
+	return RawMethod(fmt.Sprintf(`<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><stream>%s</stream></create-subscription>`, stream))
+}
+
 var msgID = uuid
 
 // uuid generates a "good enough" uuid without adding external dependencies
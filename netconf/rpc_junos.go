@@ -0,0 +1,190 @@
+// Go NETCONF Client - Juniper Junos configuration RPCs
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Formats accepted by the Junos configuration RPCs.
+const (
+	JunosFormatText = "text"
+	JunosFormatSet  = "set"
+	JunosFormatXML  = "xml"
+	JunosFormatJSON = "json"
+)
+
+// Actions accepted by MethodLoadConfiguration.
+const (
+	JunosActionMerge    = "merge"
+	JunosActionReplace  = "replace"
+	JunosActionOverride = "override"
+	JunosActionUpdate   = "update"
+	JunosActionSet      = "set"
+)
+
+// Configuration databases accepted by MethodOpenConfiguration.  Junos has no
+// exclusive database: the configure exclusive mode is the global candidate
+// configuration locked with MethodLockConfiguration.
+const (
+	JunosDatabasePrivate = "private"
+	JunosDatabaseDynamic = "dynamic"
+)
+
+// MethodLoadConfiguration files a Junos load-configuration request with the
+// remote host.
+//
+// config is wrapped according to format: text is sent as configuration-text,
+// set as configuration-set, json as configuration-json and xml is sent as-is,
+// so it must contain the <configuration> element.  The set format always uses
+// the set action, as required by Junos.  An empty action omits the attribute,
+// Junos then merging the configuration.
+func MethodLoadConfiguration(format string, action string, config string) RawMethod {
+	var body string
+	switch format {
+	case JunosFormatText:
+		body = "<configuration-text>" + escapeXML(config) + "</configuration-text>"
+	case JunosFormatSet:
+		// Junos expects set commands as text loaded with the set action
+		format, action = JunosFormatText, JunosActionSet
+		body = "<configuration-set>" + escapeXML(config) + "</configuration-set>"
+	case JunosFormatJSON:
+		body = "<configuration-json>" + escapeXML(config) + "</configuration-json>"
+	default:
+		body = config
+	}
+
+	if action == "" {
+		return RawMethod(fmt.Sprintf("<load-configuration format=\"%s\">%s</load-configuration>", format, body))
+	}
+	return RawMethod(fmt.Sprintf("<load-configuration action=\"%s\" format=\"%s\">%s</load-configuration>", action, format, body))
+}
+
+// CommitOptions holds the optional parameters of a Junos commit-configuration
+// request.
+type CommitOptions struct {
+	// Check only validates the candidate configuration without committing it
+	Check bool
+	// Synchronize commits on both Routing Engines
+	Synchronize bool
+	// ForceSynchronize synchronizes even if the other Routing Engine has
+	// uncommitted changes or is locked
+	ForceSynchronize bool
+	// Comment is recorded in the commit history
+	Comment string
+	// Confirmed requires a confirming commit within ConfirmTimeout minutes
+	// or the configuration is rolled back
+	Confirmed      bool
+	ConfirmTimeout int
+}
+
+// MethodCommitConfiguration files a Junos commit-configuration request with
+// the remote host.
+func MethodCommitConfiguration(opts CommitOptions) RawMethod {
+	var buf bytes.Buffer
+
+	buf.WriteString("<commit-configuration>")
+	if opts.Check {
+		buf.WriteString("<check/>")
+	}
+	if opts.Synchronize {
+		buf.WriteString("<synchronize/>")
+	}
+	if opts.ForceSynchronize {
+		buf.WriteString("<force-synchronize/>")
+	}
+	if opts.Confirmed {
+		buf.WriteString("<confirmed/>")
+		if opts.ConfirmTimeout > 0 {
+			fmt.Fprintf(&buf, "<confirm-timeout>%d</confirm-timeout>", opts.ConfirmTimeout)
+		}
+	}
+	if opts.Comment != "" {
+		buf.WriteString("<log>" + escapeXML(opts.Comment) + "</log>")
+	}
+	buf.WriteString("</commit-configuration>")
+
+	return RawMethod(buf.String())
+}
+
+// MethodOpenConfiguration files a Junos open-configuration request with the
+// remote host for the given database, JunosDatabasePrivate or
+// JunosDatabaseDynamic.  Other databases are rejected.
+func MethodOpenConfiguration(database string) (RawMethod, error) {
+	switch database {
+	case JunosDatabasePrivate, JunosDatabaseDynamic:
+	default:
+		return "", fmt.Errorf("netconf: unknown configuration database %q", database)
+	}
+	return RawMethod(fmt.Sprintf("<open-configuration><%s/></open-configuration>", database)), nil
+}
+
+// MethodCloseConfiguration files a Junos close-configuration request with the
+// remote host, discarding the database opened with MethodOpenConfiguration.
+func MethodCloseConfiguration() RawMethod {
+	return RawMethod("<close-configuration/>")
+}
+
+// MethodLockConfiguration files a Junos lock-configuration request with the
+// remote host, locking the candidate configuration like configure exclusive.
+func MethodLockConfiguration() RawMethod {
+	return RawMethod("<lock-configuration/>")
+}
+
+// MethodUnlockConfiguration files a Junos unlock-configuration request with
+// the remote host, releasing the lock taken with MethodLockConfiguration.
+func MethodUnlockConfiguration() RawMethod {
+	return RawMethod("<unlock-configuration/>")
+}
+
+// xmlEscaper escapes text embedded as XML character data while keeping line
+// breaks readable, unlike xml.EscapeText
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escapeXML(s string) string {
+	return xmlEscaper.Replace(s)
+}
+
+// MethodGetConfiguration files a Junos get-configuration request with the
+// remote host, returning the committed configuration in the given format
+// (text, set, xml or json).
+func MethodGetConfiguration(format string) RawMethod {
+	return RawMethod(fmt.Sprintf("<get-configuration database=\"committed\" format=\"%s\"/>", format))
+}
+
+// MethodCompareConfiguration files a Junos get-configuration request with the
+// remote host, returning the differences between the candidate configuration
+// and the given rollback in text format.
+func MethodCompareConfiguration(rollback int) RawMethod {
+	return RawMethod(fmt.Sprintf("<get-configuration compare=\"rollback\" rollback=\"%d\" format=\"text\"/>", rollback))
+}
+
+// MethodGetRollbackInformation files a Junos get-rollback-information request
+// with the remote host, returning the differences between the rollback and
+// compare configurations in text format.  A negative compare returns the
+// rollback configuration itself.
+func MethodGetRollbackInformation(rollback int, compare int) RawMethod {
+	if compare < 0 {
+		return RawMethod(fmt.Sprintf("<get-rollback-information><rollback>%d</rollback><format>text</format></get-rollback-information>", rollback))
+	}
+	return RawMethod(fmt.Sprintf("<get-rollback-information><rollback>%d</rollback><compare>%d</compare><format>text</format></get-rollback-information>", rollback, compare))
+}
+
+// MethodLoadRollback files a Junos load-configuration request with the remote
+// host, replacing the candidate configuration with the given rollback.
+func MethodLoadRollback(rollback int) RawMethod {
+	return RawMethod(fmt.Sprintf("<load-configuration rollback=\"%d\"/>", rollback))
+}
+
+// MethodCommand files a Junos command request with the remote host, running
+// an operational mode CLI command with the output in the given format (text,
+// xml or json).
+func MethodCommand(command string, format string) RawMethod {
+	return RawMethod(fmt.Sprintf("<command format=\"%s\">%s</command>", format, escapeXML(command)))
+}
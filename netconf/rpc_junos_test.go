@@ -0,0 +1,158 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"testing"
+)
+
+func TestMethodLoadConfiguration(t *testing.T) {
+	tt := []struct {
+		name     string
+		format   string
+		action   string
+		config   string
+		expected string
+	}{
+		{
+			name:     "text",
+			format:   JunosFormatText,
+			action:   JunosActionMerge,
+			config:   "system { host-name r1; }",
+			expected: `<load-configuration action="merge" format="text"><configuration-text>system { host-name r1; }</configuration-text></load-configuration>`,
+		},
+		{
+			name:     "set",
+			format:   JunosFormatSet,
+			action:   JunosActionMerge,
+			config:   "set system host-name r1\nset interfaces ge-0/0/0 description \"a & b\"",
+			expected: "<load-configuration action=\"set\" format=\"text\"><configuration-set>set system host-name r1\nset interfaces ge-0/0/0 description \"a &amp; b\"</configuration-set></load-configuration>",
+		},
+		{
+			name:     "xml",
+			format:   JunosFormatXML,
+			action:   JunosActionReplace,
+			config:   "<configuration><system><host-name>r1</host-name></system></configuration>",
+			expected: `<load-configuration action="replace" format="xml"><configuration><system><host-name>r1</host-name></system></configuration></load-configuration>`,
+		},
+		{
+			name:     "default action",
+			format:   JunosFormatText,
+			config:   "system { host-name r1; }",
+			expected: `<load-configuration format="text"><configuration-text>system { host-name r1; }</configuration-text></load-configuration>`,
+		},
+		{
+			name:     "json",
+			format:   JunosFormatJSON,
+			action:   JunosActionOverride,
+			config:   `{"configuration":{"system":{"host-name":"r1"}}}`,
+			expected: `<load-configuration action="override" format="json"><configuration-json>{"configuration":{"system":{"host-name":"r1"}}}</configuration-json></load-configuration>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			m := MethodLoadConfiguration(tc.format, tc.action, tc.config)
+			if m.MarshalMethod() != tc.expected {
+				t.Errorf("got %s, expected %s", m, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMethodCommitConfiguration(t *testing.T) {
+	tt := []struct {
+		name     string
+		opts     CommitOptions
+		expected string
+	}{
+		{
+			name:     "default",
+			expected: "<commit-configuration></commit-configuration>",
+		},
+		{
+			name:     "check",
+			opts:     CommitOptions{Check: true},
+			expected: "<commit-configuration><check/></commit-configuration>",
+		},
+		{
+			name: "all",
+			opts: CommitOptions{
+				Synchronize:    true,
+				Comment:        "change <1>",
+				Confirmed:      true,
+				ConfirmTimeout: 5,
+			},
+			expected: "<commit-configuration><synchronize/><confirmed/><confirm-timeout>5</confirm-timeout><log>change &lt;1&gt;</log></commit-configuration>",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			m := MethodCommitConfiguration(tc.opts)
+			if m.MarshalMethod() != tc.expected {
+				t.Errorf("got %s, expected %s", m, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMethodGetConfiguration(t *testing.T) {
+	expected := `<get-configuration database="committed" format="set"/>`
+
+	m := MethodGetConfiguration(JunosFormatSet)
+	if m.MarshalMethod() != expected {
+		t.Errorf("got %s, expected %s", m, expected)
+	}
+}
+
+func TestMethodOpenConfiguration(t *testing.T) {
+	tt := []struct {
+		database string
+		expected string
+		err      string
+	}{
+		{JunosDatabasePrivate, "<open-configuration><private/></open-configuration>", ""},
+		{JunosDatabaseDynamic, "<open-configuration><dynamic/></open-configuration>", ""},
+		{"exclusive", "", `netconf: unknown configuration database "exclusive"`},
+		{"private/><x", "", `netconf: unknown configuration database "private/><x"`},
+	}
+
+	for _, tc := range tt {
+		mOpen, err := MethodOpenConfiguration(tc.database)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%s: got error %v, expected %s", tc.database, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.database, err)
+		}
+		if mOpen.MarshalMethod() != tc.expected {
+			t.Errorf("got %s, expected %s", mOpen, tc.expected)
+		}
+	}
+}
+
+func TestMethodLockConfiguration(t *testing.T) {
+	if m := MethodLockConfiguration(); m.MarshalMethod() != "<lock-configuration/>" {
+		t.Errorf("got %s, expected <lock-configuration/>", m)
+	}
+	if m := MethodUnlockConfiguration(); m.MarshalMethod() != "<unlock-configuration/>" {
+		t.Errorf("got %s, expected <unlock-configuration/>", m)
+	}
+}
+
+func TestMethodCloseConfiguration(t *testing.T) {
+	expected := "<close-configuration/>"
+
+	mClose := MethodCloseConfiguration()
+	if mClose.MarshalMethod() != expected {
+		t.Errorf("got %s, expected %s", mClose, expected)
+	}
+}
@@ -9,6 +9,7 @@ package netconf
 import (
 	"bytes"
 	"encoding/xml"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -103,6 +104,24 @@ func TestMethodGetConfig(t *testing.T) {
 	}
 }
 
+func TestMethodGetConfigFilter(t *testing.T) {
+	expected := `<get-config><source><running/></source><filter type="subtree"><system/></filter></get-config>`
+
+	mGetConfig := MethodGetConfigFilter("running", "subtree", "<system/>")
+	if mGetConfig.MarshalMethod() != expected {
+		t.Errorf("got %s, expected %s", mGetConfig, expected)
+	}
+}
+
+func TestMethodCommit(t *testing.T) {
+	expected := "<commit/>"
+
+	mCommit := MethodCommit()
+	if mCommit.MarshalMethod() != expected {
+		t.Errorf("got %s, expected %s", mCommit, expected)
+	}
+}
+
 // TestUUIDLength verifies that UUID length is cor([a-zA-Z]|\d|-)rect
 func TestUUIDLength(t *testing.T) {
 	expectedLength := 36
@@ -115,6 +134,15 @@ func TestUUIDLength(t *testing.T) {
 	}
 }
 
+func TestMethodCreateSubscription(t *testing.T) {
+	expected := `<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><stream>what.stream</stream></create-subscription>`
+
+	mSubscription := MethodCreateSubscription("what.stream")
+	if mSubscription.MarshalMethod() != expected {
+		t.Errorf("got %s, expected %s", mSubscription, expected)
+	}
+}
+
 // TestUUIDChat verifies that UUID contains ASCII letter/number and delimiter
 func TestUUIDChar(t *testing.T) {
 	//validChars := regexp.MustCompile("([a-zA-Z]|\\d|-)")
@@ -156,6 +184,7 @@ func TestUUIDChar(t *testing.T) {
 var RPCReplytests = []struct {
 	rawXML  string
 	replyOk bool
+	errMsg  string
 }{
 	{
 		`
@@ -165,6 +194,7 @@ var RPCReplytests = []struct {
 <ok/>
 </rpc-reply>`,
 		false,
+		"",
 	},
 	{
 		`
@@ -191,6 +221,7 @@ configuration check-out failed: (missing mandatory statements)
 </commit-results>
 </rpc-reply>`,
 		false,
+		"netconf rpc [error] 'mgd: Missing mandatory statement: 'root-authentication''",
 	},
 	{
 		`
@@ -220,15 +251,19 @@ configuration check-out failed: (missing mandatory statements)
 <ok/>
 </rpc-reply>`,
 		false,
+		"",
 	},
 }
 
 func TestNewRPCReply(t *testing.T) {
 	for _, tc := range RPCReplytests {
 		reply, err := newRPCReply([]byte(tc.rawXML), false, "101")
-		if err != nil {
+		if tc.errMsg == "" && err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
+		if tc.errMsg != "" && (err == nil || err.Error() != tc.errMsg) {
+			t.Fatalf("newRPCReply(%q) got error %v, expected %s", tc.rawXML, err, tc.errMsg)
+		}
 		if reply.RawReply != tc.rawXML {
 			t.Errorf("newRPCReply(%q) did not set RawReply to input, got %q", tc.rawXML, reply.RawReply)
 		}
@@ -237,3 +272,104 @@ func TestNewRPCReply(t *testing.T) {
 		}
 	}
 }
+
+func TestNewRPCReplyNestedErrors(t *testing.T) {
+	reply, err := newRPCReply([]byte(RPCReplytests[2].rawXML), false, "101")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reply.Errors) != 2 || reply.Errors[1].Path != "[edit protocols]" {
+		t.Errorf("unexpected errors %+v", reply.Errors)
+	}
+
+	_, err = newRPCReply([]byte(RPCReplytests[2].rawXML), true, "101")
+	if err == nil || err.Error() != "netconf rpc [warning] 'mgd: requires 'mpls' license'" {
+		t.Errorf("expected warning to be returned as error, got %v", err)
+	}
+}
+
+var junosErrorReply = `
+<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/17.3R1/junos">
+<load-configuration-results>
+<xnm:warning xmlns="http://xml.juniper.net/xnm/1.1/xnm" xmlns:xnm="http://xml.juniper.net/xnm/1.1/xnm">
+<source-daemon>mgd</source-daemon>
+<message>statement has no contents; ignored</message>
+</xnm:warning>
+<xnm:error xmlns="http://xml.juniper.net/xnm/1.1/xnm" xmlns:xnm="http://xml.juniper.net/xnm/1.1/xnm">
+<source-daemon>dcd</source-daemon>
+<edit-path>[edit interfaces ge-0/0/0]</edit-path>
+<statement>unit 0</statement>
+<message>
+mtu not supported
+</message>
+</xnm:error>
+</load-configuration-results>
+</rpc-reply>`
+
+func TestNewRPCReplyJunosErrors(t *testing.T) {
+	expected := []XNMError{
+		{
+			Severity:     "warning",
+			SourceDaemon: "mgd",
+			Message:      "statement has no contents; ignored",
+		},
+		{
+			Severity:     "error",
+			SourceDaemon: "dcd",
+			EditPath:     "[edit interfaces ge-0/0/0]",
+			Statement:    "unit 0",
+			Message:      "\nmtu not supported\n",
+		},
+	}
+
+	reply, err := newRPCReply([]byte(junosErrorReply), false, "101")
+	xnmErr, ok := err.(*XNMError)
+	if !ok {
+		t.Fatalf("expected *XNMError, got %v", err)
+	}
+	if xnmErr.Error() != "netconf junos [error] 'mtu not supported'" {
+		t.Errorf("unexpected error string %q", xnmErr.Error())
+	}
+	if !cmp.Equal(reply.JunosErrors, expected) {
+		t.Errorf("unexpected junos errors:\n%s", cmp.Diff(expected, reply.JunosErrors))
+	}
+
+	_, err = newRPCReply([]byte(strings.Replace(junosErrorReply, "xnm:error", "xnm:warning", -1)), true, "101")
+	if xnmErr, ok := err.(*XNMError); !ok || xnmErr.Severity != "warning" {
+		t.Errorf("expected warning to be returned as error, got %v", err)
+	}
+}
+
+func TestNewRPCReplyJunosAttrs(t *testing.T) {
+	rawXML := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/17.3R1/junos">
+<interface-information xmlns="http://xml.juniper.net/junos/17.3R1/junos-interface" junos:style="terse">
+<physical-interface junos:style="brief"><name>ge-0/0/0</name></physical-interface>
+</interface-information>
+<xnm:warning xmlns:xnm="http://xml.juniper.net/xnm/1.1/xnm" junos:seconds="1514764800">
+<message>interface flapping</message>
+</xnm:warning>
+</rpc-reply>`
+
+	reply, err := newRPCReply([]byte(rawXML), false, "101")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reply.Style != "terse" {
+		t.Errorf("got style %q, expected terse", reply.Style)
+	}
+	if diff := cmp.Diff(map[string]string{"style": "terse"}, reply.JunosAttrs); diff != "" {
+		t.Errorf("junos attributes mismatch (-want +got):\n%s", diff)
+	}
+	if len(reply.JunosErrors) != 1 || reply.JunosErrors[0].JunosAttrs["seconds"] != "1514764800" {
+		t.Errorf("unexpected junos errors %+v", reply.JunosErrors)
+	}
+
+	// Attributes using an undeclared prefix are recognized too
+	reply, err = newRPCReply([]byte(`<rpc-reply><configuration junos:changed-seconds="1514764800"/></rpc-reply>`), false, "101")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reply.Style != "" || reply.JunosAttrs["changed-seconds"] != "1514764800" {
+		t.Errorf("unexpected attributes %q %v", reply.Style, reply.JunosAttrs)
+	}
+}
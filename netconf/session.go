@@ -11,8 +11,10 @@ Package netconf provides support for a a simple NETCONF client based on RFC6241
 package netconf
 
 import (
+	"context"
 	"encoding/xml"
 	"strings"
+	"time"
 )
 
 // Session defines the necessary components for a NETCONF session
@@ -21,6 +23,18 @@ type Session struct {
 	SessionID          int
 	ServerCapabilities []string
 	ErrOnWarning       bool
+	// Hooks are called for every message sent or received, set to
+	// DefaultMessageHooks by NewSession
+	Hooks []MessageHook
+	// Metrics receives the measurements of the session, set to
+	// DefaultMetrics by NewSession
+	Metrics Metrics
+	// Tracer creates the spans of the rpcs executed, set to DefaultTracer by
+	// NewSession
+	Tracer Tracer
+
+	// version is the framing version negotiated
+	version string
 }
 
 // Close is used to close and end a transport session
@@ -28,54 +42,178 @@ func (s *Session) Close() error {
 	return s.Transport.Close()
 }
 
-// Exec is used to execute an RPC method or methods
+// Exec is used to execute an RPC method or methods.  When the reply holds an
+// rpc-error or a Junos xnm:error, or a warning with ErrOnWarning, the error
+// is returned together with the non-nil reply so that its other errors and
+// data remain available.
 func (s *Session) Exec(methods ...RPCMethod) (*RPCReply, error) {
+	return s.ExecContext(context.Background(), methods...)
+}
+
+// ExecContext is used to execute an RPC method or methods, giving up when ctx
+// is done.  As the reply can no longer be matched with its request, the
+// transport is closed when ctx is done while waiting for the reply.  Errors
+// reported by the reply are returned along with it, as for Exec.
+func (s *Session) ExecContext(ctx context.Context, methods ...RPCMethod) (reply *RPCReply, err error) {
 	rpc := NewRPCMessage(methods)
+	if s.Tracer != nil {
+		var span Span
+		ctx, span = s.Tracer.Start(ctx, SpanRPC,
+			Attribute{AttrSessionID, s.SessionID},
+			Attribute{AttrOperation, operationName(methods)},
+			Attribute{AttrMessageID, rpc.MessageID},
+		)
+		defer func() { endRPCSpan(span, reply, err) }()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		return s.exec(rpc)
+	}
+
+	type result struct {
+		reply *RPCReply
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		reply, err := s.exec(rpc)
+		done <- result{reply, err}
+	}()
 
+	select {
+	case res := <-done:
+		return res.reply, res.err
+	case <-ctx.Done():
+		s.Transport.Close()
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Session) exec(rpc *RPCMessage) (reply *RPCReply, err error) {
 	request, err := xml.Marshal(rpc)
 	if err != nil {
 		return nil, err
 	}
 
+	var sent bool
+	var rawXML []byte
+	start := time.Now()
+	if s.Metrics != nil {
+		defer func() {
+			if !sent {
+				request = nil
+			}
+			s.observe(rpc.Methods, request, rawXML, reply, time.Since(start), err)
+		}()
+	}
+
 	err = s.Transport.Send(request)
+	s.notify(&MessageEvent{Direction: MessageSent, MessageID: rpc.MessageID, Message: request, Duration: time.Since(start), Err: err})
 	if err != nil {
 		return nil, err
 	}
+	sent = true
 
-	rawXML, err := s.Transport.Receive()
+	rawXML, err = s.Transport.Receive()
+	s.notify(&MessageEvent{Direction: MessageReceived, MessageID: rpc.MessageID, Message: rawXML, Duration: time.Since(start), Err: err})
 	if err != nil {
 		return nil, err
 	}
 
-	reply, err := newRPCReply(rawXML, s.ErrOnWarning, rpc.MessageID)
-	if err != nil {
-		return nil, err
+	return newRPCReply(rawXML, s.ErrOnWarning, rpc.MessageID)
+}
+
+// Get retrieves operational and configuration data matching the subtree
+// filter and unmarshals it into dst.  An empty filter retrieves everything.
+// See RPCReply.Unmarshal for how dst is decoded.
+func (s *Session) Get(ctx context.Context, filter string, dst interface{}) error {
+	method := RawMethod("<get/>")
+	if filter != "" {
+		method = MethodGet("subtree", filter)
 	}
+	return s.execUnmarshal(ctx, method, dst)
+}
 
-	return reply, nil
+// GetConfig retrieves the source configuration matching the subtree filter
+// and unmarshals it into dst.  An empty filter retrieves everything.  See
+// RPCReply.Unmarshal for how dst is decoded.
+func (s *Session) GetConfig(ctx context.Context, source string, filter string, dst interface{}) error {
+	method := MethodGetConfig(source)
+	if filter != "" {
+		method = MethodGetConfigFilter(source, "subtree", filter)
+	}
+	return s.execUnmarshal(ctx, method, dst)
+}
+
+func (s *Session) execUnmarshal(ctx context.Context, method RPCMethod, dst interface{}) error {
+	reply, err := s.ExecContext(ctx, method)
+	if err != nil {
+		return err
+	}
+	return reply.Unmarshal(dst)
 }
 
 // NewSession creates a new NETCONF session using the provided transport layer.
 func NewSession(t Transport) *Session {
+	return NewSessionContext(context.Background(), t)
+}
+
+// NewSessionContext creates a new NETCONF session using the provided
+// transport layer, the hello exchange being traced as a child of ctx.
+func NewSessionContext(ctx context.Context, t Transport) *Session {
 	s := new(Session)
 	s.Transport = t
+	s.Hooks = DefaultMessageHooks
+	s.Metrics = DefaultMetrics
+	s.Tracer = DefaultTracer
+
+	_, span := startSpan(ctx, s.Tracer, SpanHello)
 
 	// Receive Servers Hello message
-	serverHello, _ := t.ReceiveHello()
+	start := time.Now()
+	serverHello, helloErr := t.ReceiveHello()
 	s.SessionID = serverHello.SessionID
 	s.ServerCapabilities = serverHello.Capabilities
+	if len(s.Hooks) > 0 {
+		msg, _ := xml.Marshal(serverHello)
+		s.notify(&MessageEvent{Direction: MessageReceived, Message: msg, Duration: time.Since(start), Err: helloErr})
+	}
 
 	// Send our hello using default capabilities.
-	t.SendHello(&HelloMessage{Capabilities: DefaultCapabilities})
+	hello := &HelloMessage{Capabilities: DefaultCapabilities}
+	start = time.Now()
+	err := t.SendHello(hello)
+	if len(s.Hooks) > 0 {
+		msg, _ := xml.Marshal(hello)
+		s.notify(&MessageEvent{Direction: MessageSent, Message: append([]byte(xml.Header), msg...), Duration: time.Since(start), Err: err})
+	}
 
 	// Set Transport version
-	t.SetVersion("v1.0")
+	s.version = "v1.0"
 	for _, capability := range s.ServerCapabilities {
 		if strings.Contains(capability, "urn:ietf:params:netconf:base:1.1") {
-			t.SetVersion("v1.1")
+			s.version = "v1.1"
 			break
 		}
 	}
+	t.SetVersion(s.version)
+
+	if helloErr == nil {
+		helloErr = err
+	}
+	span.SetAttributes(Attribute{AttrSessionID, s.SessionID})
+	endSpan(span, helloErr)
+
+	if s.Metrics != nil {
+		if helloErr != nil {
+			s.Metrics.SessionFailed(helloErr)
+		} else {
+			s.Metrics.SessionOpened(s.version)
+		}
+	}
 
 	return s
 }
@@ -0,0 +1,30 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package netconf
+
+import (
+	"context"
+)
+
+// Get retrieves operational and configuration data matching the subtree
+// filter and returns it decoded as a T.  See Session.Get.
+func Get[T any](ctx context.Context, s *Session, filter string) (T, error) {
+	var v T
+	err := s.Get(ctx, filter, &v)
+	return v, err
+}
+
+// GetConfig retrieves the source configuration matching the subtree filter
+// and returns it decoded as a T.  See Session.GetConfig.
+func GetConfig[T any](ctx context.Context, s *Session, source string, filter string) (T, error) {
+	var v T
+	err := s.GetConfig(ctx, source, filter, &v)
+	return v, err
+}
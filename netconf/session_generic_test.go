@@ -0,0 +1,39 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package netconf
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetGeneric(t *testing.T) {
+	s, _ := newSessionTest(testDataReply)
+
+	v, err := Get[testSystem](context.Background(), s, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.HostName != "r1" {
+		t.Errorf("got host-name %q, expected r1", v.HostName)
+	}
+}
+
+func TestGetConfigGeneric(t *testing.T) {
+	s, _ := newSessionTest(testDataReply)
+
+	v, err := GetConfig[testSystem](context.Background(), s, "running", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.HostName != "r1" {
+		t.Errorf("got host-name %q, expected r1", v.HostName)
+	}
+}
@@ -0,0 +1,280 @@
+// Go NETCONF Client - Juniper Junos session helpers
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ConfigDiff is a Junos configuration difference in text format as produced
+// by "show | compare".
+type ConfigDiff struct {
+	// Text is the difference as returned by the device
+	Text     string
+	Sections []ConfigDiffSection
+}
+
+// ConfigDiffSection groups the changed lines under a hierarchy level such as
+// "[edit system]".
+type ConfigDiffSection struct {
+	// Path is the hierarchy level without brackets (e.g. "edit system")
+	Path  string
+	Lines []ConfigDiffLine
+}
+
+// ConfigDiffLine is a single line of a configuration difference.  Op is "+"
+// for added lines, "-" for removed lines and " " for context lines.
+type ConfigDiffLine struct {
+	Op   string
+	Text string
+}
+
+// Empty reports whether the difference contains no changes
+func (d *ConfigDiff) Empty() bool {
+	return len(d.Sections) == 0
+}
+
+// ParseConfigDiff parses a Junos configuration difference in text format
+func ParseConfigDiff(text string) *ConfigDiff {
+	diff := &ConfigDiff{Text: text}
+
+	var section *ConfigDiffSection
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			diff.Sections = append(diff.Sections, ConfigDiffSection{Path: line[1 : len(line)-1]})
+			section = &diff.Sections[len(diff.Sections)-1]
+			continue
+		}
+
+		// Changes reported before any hierarchy level apply to the top
+		if section == nil {
+			diff.Sections = append(diff.Sections, ConfigDiffSection{Path: "edit"})
+			section = &diff.Sections[len(diff.Sections)-1]
+		}
+
+		op := " "
+		if line[0] == '+' || line[0] == '-' {
+			op, line = line[:1], line[1:]
+		}
+		section.Lines = append(section.Lines, ConfigDiffLine{Op: op, Text: strings.TrimSpace(line)})
+	}
+
+	return diff
+}
+
+// CompareRollback returns the differences between the candidate configuration
+// and the given rollback.
+func (s *Session) CompareRollback(rollback int) (*ConfigDiff, error) {
+	return s.CompareRollbackContext(context.Background(), rollback)
+}
+
+// CompareRollbackContext is CompareRollback giving up when ctx is done.  See
+// ExecContext.
+func (s *Session) CompareRollbackContext(ctx context.Context, rollback int) (*ConfigDiff, error) {
+	return s.execConfigDiff(ctx, MethodCompareConfiguration(rollback))
+}
+
+// CompareRollbacks returns the differences between two rollback
+// configurations, as the changes needed to go from compare to rollback.
+func (s *Session) CompareRollbacks(rollback int, compare int) (*ConfigDiff, error) {
+	return s.CompareRollbacksContext(context.Background(), rollback, compare)
+}
+
+// CompareRollbacksContext is CompareRollbacks giving up when ctx is done.
+// See ExecContext.
+func (s *Session) CompareRollbacksContext(ctx context.Context, rollback int, compare int) (*ConfigDiff, error) {
+	return s.execConfigDiff(ctx, MethodGetRollbackInformation(rollback, compare))
+}
+
+// LoadRollback replaces the candidate configuration with the given rollback.
+// The change still has to be committed.
+func (s *Session) LoadRollback(rollback int) error {
+	return s.LoadRollbackContext(context.Background(), rollback)
+}
+
+// LoadRollbackContext is LoadRollback giving up when ctx is done.  See
+// ExecContext.
+func (s *Session) LoadRollbackContext(ctx context.Context, rollback int) error {
+	_, err := s.ExecContext(ctx, MethodLoadRollback(rollback))
+	return err
+}
+
+func (s *Session) execConfigDiff(ctx context.Context, method RPCMethod) (*ConfigDiff, error) {
+	reply, err := s.ExecContext(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := configurationOutput(reply.RawReply)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfigDiff(output), nil
+}
+
+// configurationOutput returns the text of the first configuration-output
+// element of a reply, wherever Junos placed it.
+func configurationOutput(rawXML string) (string, error) {
+	return elementText(rawXML, "configuration-output")
+}
+
+// elementText returns the text of the first element called name of a reply,
+// wherever Junos placed it.
+func elementText(rawXML string, name string) (string, error) {
+	d := xml.NewDecoder(strings.NewReader(rawXML))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return "", fmt.Errorf("netconf: no %s in reply", name)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == name {
+			var output string
+			err := d.DecodeElement(&output, &start)
+			return output, err
+		}
+	}
+}
+
+// GetConfiguration returns the committed configuration of a Junos device in
+// the given format: the text of the configuration for the text and set
+// formats, the configuration element for the xml format and the JSON
+// document for the json format.  See ExecContext for ctx.
+func (s *Session) GetConfiguration(ctx context.Context, format string) (string, error) {
+	reply, err := s.ExecContext(ctx, MethodGetConfiguration(format))
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case JunosFormatText:
+		return elementText(reply.RawReply, "configuration-text")
+	case JunosFormatSet:
+		return elementText(reply.RawReply, "configuration-set")
+	case JunosFormatJSON:
+		return elementText(reply.RawReply, "rpc-reply")
+	}
+	return strings.TrimSpace(reply.Data), nil
+}
+
+// CommandReply defines a reply to a Junos command request
+type CommandReply struct {
+	// Format is the format the output was requested in
+	Format string
+	// Output holds the command output: the text of the output elements for
+	// the text format, the JSON document for the json format and the raw
+	// XML for the xml format
+	Output   string
+	Warnings []XNMError
+	Reply    *RPCReply
+}
+
+// Unmarshal decodes the output of a xml or json command into v.  For the xml
+// format v describes the children of the rpc-reply element.
+func (r *CommandReply) Unmarshal(v interface{}) error {
+	switch r.Format {
+	case JunosFormatJSON:
+		return json.Unmarshal([]byte(r.Output), v)
+	case JunosFormatXML:
+		return xml.Unmarshal([]byte(r.Reply.RawReply), v)
+	}
+	return fmt.Errorf("netconf: cannot unmarshal command output in %s format", r.Format)
+}
+
+// RunCommand runs an operational mode CLI command (e.g. "show version") on a
+// Junos device and returns its output in the given format (text, xml or
+// json).  Junos errors are returned as *XNMError like any other reply.
+func (s *Session) RunCommand(command string, format string) (*CommandReply, error) {
+	return s.RunCommandContext(context.Background(), command, format)
+}
+
+// RunCommandContext is RunCommand giving up when ctx is done.  See
+// ExecContext.
+func (s *Session) RunCommandContext(ctx context.Context, command string, format string) (*CommandReply, error) {
+	reply, err := s.ExecContext(ctx, MethodCommand(command, format))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCommandReply(reply, format)
+}
+
+// parseCommandReply extracts the command output and the Junos warnings from
+// reply
+func parseCommandReply(reply *RPCReply, format string) (*CommandReply, error) {
+	cr := &CommandReply{Format: format, Reply: reply}
+	if format == JunosFormatXML {
+		cr.Output = reply.Data
+	}
+	for _, xe := range reply.JunosErrors {
+		if xe.Severity == "warning" {
+			cr.Warnings = append(cr.Warnings, xe)
+		}
+	}
+
+	var output strings.Builder
+
+	d := xml.NewDecoder(strings.NewReader(reply.RawReply))
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case isXNMError(tok):
+				if err := d.Skip(); err != nil {
+					return nil, err
+				}
+			case format == JunosFormatText && (tok.Name.Local == "output" || tok.Name.Local == "configuration-output"):
+				var text string
+				if err := d.DecodeElement(&text, &tok); err != nil {
+					return nil, err
+				}
+				output.WriteString(text)
+			default:
+				depth++
+			}
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			// JSON output is returned as the text of rpc-reply
+			if format == JunosFormatJSON && depth == 1 {
+				output.Write(tok)
+			}
+		}
+	}
+
+	if format != JunosFormatXML {
+		cr.Output = output.String()
+		if format == JunosFormatJSON {
+			cr.Output = strings.TrimSpace(cr.Output)
+		}
+	}
+	return cr, nil
+}
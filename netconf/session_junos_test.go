@@ -0,0 +1,302 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testConfigDiff = `
+[edit system]
+-  host-name r1;
++  host-name r2;
+[edit interfaces ge-0/0/0]
+   unit 0 {
++      description uplink;
+   }
+`
+
+func TestParseConfigDiff(t *testing.T) {
+	expected := &ConfigDiff{
+		Text: testConfigDiff,
+		Sections: []ConfigDiffSection{
+			{
+				Path: "edit system",
+				Lines: []ConfigDiffLine{
+					{Op: "-", Text: "host-name r1;"},
+					{Op: "+", Text: "host-name r2;"},
+				},
+			},
+			{
+				Path: "edit interfaces ge-0/0/0",
+				Lines: []ConfigDiffLine{
+					{Op: " ", Text: "unit 0 {"},
+					{Op: "+", Text: "description uplink;"},
+					{Op: " ", Text: "}"},
+				},
+			},
+		},
+	}
+
+	diff := ParseConfigDiff(testConfigDiff)
+	if !cmp.Equal(diff, expected) {
+		t.Errorf("unexpected diff:\n%s", cmp.Diff(expected, diff))
+	}
+
+	if ParseConfigDiff("\n").Empty() != true {
+		t.Errorf("diff of blank output should be empty")
+	}
+}
+
+func TestSessionCompareRollback(t *testing.T) {
+	s, out := newSessionTest(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/17.3R1/junos">
+<configuration-information>
+<configuration-output>` + testConfigDiff + `</configuration-output>
+</configuration-information>
+</rpc-reply>`)
+
+	diff, err := s.CompareRollback(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diff.Sections) != 2 {
+		t.Errorf("got %d sections, expected 2", len(diff.Sections))
+	}
+	if !strings.Contains(out.String(), `<get-configuration compare="rollback" rollback="1" format="text"/>`) {
+		t.Errorf("unexpected request %q", out.String())
+	}
+}
+
+func TestSessionCompareRollbacksNoOutput(t *testing.T) {
+	s, _ := newSessionTest(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>`)
+
+	if _, err := s.CompareRollbacks(0, 1); err == nil {
+		t.Errorf("expected error for reply without configuration-output")
+	}
+}
+
+func TestSessionLoadRollback(t *testing.T) {
+	s, out := newSessionTest(testOkReply)
+
+	if err := s.LoadRollback(2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `<load-configuration rollback="2"/>`) {
+		t.Errorf("unexpected request %q", out.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.LoadRollbackContext(ctx, 2); err != context.Canceled {
+		t.Errorf("got error %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestSessionGetConfiguration(t *testing.T) {
+	tt := []struct {
+		format   string
+		reply    string
+		expected string
+	}{
+		{
+			format:   JunosFormatText,
+			reply:    "<configuration-text>\n## Last commit: 2018-01-01 00:00:00 UTC by admin\nsystem {\n    host-name r1;\n}\n</configuration-text>",
+			expected: "\n## Last commit: 2018-01-01 00:00:00 UTC by admin\nsystem {\n    host-name r1;\n}\n",
+		},
+		{
+			format:   JunosFormatSet,
+			reply:    "<configuration-set>\nset system host-name r1\nset interfaces ge-0/0/0 description \"a &amp; b\"\n</configuration-set>",
+			expected: "\nset system host-name r1\nset interfaces ge-0/0/0 description \"a & b\"\n",
+		},
+		{
+			format:   JunosFormatXML,
+			reply:    "\n<configuration><system><host-name>r1</host-name></system></configuration>\n",
+			expected: "<configuration><system><host-name>r1</host-name></system></configuration>",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.format, func(t *testing.T) {
+			s, out := newSessionTest(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` + tc.reply + `</rpc-reply>`)
+
+			config, err := s.GetConfiguration(context.Background(), tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config != tc.expected {
+				t.Errorf("got %q, expected %q", config, tc.expected)
+			}
+			if !strings.Contains(out.String(), string(MethodGetConfiguration(tc.format))) {
+				t.Errorf("get-configuration not sent: %s", out)
+			}
+		})
+	}
+
+	s, _ := newSessionTest(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><configuration/></rpc-reply>`)
+	if _, err := s.GetConfiguration(context.Background(), JunosFormatText); err == nil {
+		t.Errorf("expected error for reply without configuration-text")
+	}
+}
+
+func TestMethodGetRollbackInformation(t *testing.T) {
+	tt := []struct {
+		rollback int
+		compare  int
+		expected string
+	}{
+		{0, 1, "<get-rollback-information><rollback>0</rollback><compare>1</compare><format>text</format></get-rollback-information>"},
+		{3, -1, "<get-rollback-information><rollback>3</rollback><format>text</format></get-rollback-information>"},
+	}
+
+	for _, tc := range tt {
+		m := MethodGetRollbackInformation(tc.rollback, tc.compare)
+		if m.MarshalMethod() != tc.expected {
+			t.Errorf("got %s, expected %s", m, tc.expected)
+		}
+	}
+}
+
+func TestMethodLoadRollback(t *testing.T) {
+	expected := `<load-configuration rollback="2"/>`
+
+	mLoad := MethodLoadRollback(2)
+	if mLoad.MarshalMethod() != expected {
+		t.Errorf("got %s, expected %s", mLoad, expected)
+	}
+}
+
+func TestSessionRunCommand(t *testing.T) {
+	tt := []struct {
+		name     string
+		format   string
+		reply    string
+		expected string
+	}{
+		{
+			name:   "text",
+			format: JunosFormatText,
+			reply: `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:junos="http://xml.juniper.net/junos/17.3R1/junos">
+<output>
+Hostname: r1
+Model: vmx
+</output>
+</rpc-reply>`,
+			expected: "\nHostname: r1\nModel: vmx\n",
+		},
+		{
+			name:   "json",
+			format: JunosFormatJSON,
+			reply: `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+{"software-information" : [{"host-name" : [{"data" : "r1"}]}]}
+</rpc-reply>`,
+			expected: `{"software-information" : [{"host-name" : [{"data" : "r1"}]}]}`,
+		},
+		{
+			name:     "xml",
+			format:   JunosFormatXML,
+			reply:    `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><software-information><host-name>r1</host-name></software-information></rpc-reply>`,
+			expected: `<software-information><host-name>r1</host-name></software-information>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			s, out := newSessionTest(tc.reply)
+
+			cr, err := s.RunCommand("show version", tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cr.Output != tc.expected {
+				t.Errorf("got output %q, expected %q", cr.Output, tc.expected)
+			}
+
+			request := `<command format="` + tc.format + `">show version</command>`
+			if !strings.Contains(out.String(), request) {
+				t.Errorf("unexpected request %q", out.String())
+			}
+		})
+	}
+}
+
+func TestCommandReplyUnmarshal(t *testing.T) {
+	var v struct {
+		HostName string `xml:"software-information>host-name"`
+	}
+
+	s, _ := newSessionTest(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><software-information><host-name>r1</host-name></software-information></rpc-reply>`)
+	cr, err := s.RunCommand("show version", JunosFormatXML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cr.Unmarshal(&v); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.HostName != "r1" {
+		t.Errorf("got host-name %q, expected r1", v.HostName)
+	}
+}
+
+func TestSessionRunCommandXNMWarning(t *testing.T) {
+	reply := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<xnm:warning xmlns="http://xml.juniper.net/xnm/1.1/xnm" xmlns:xnm="http://xml.juniper.net/xnm/1.1/xnm">
+<source-daemon>mgd</source-daemon>
+<message>command is deprecated</message>
+</xnm:warning>
+<output>
+r1
+</output>
+</rpc-reply>`
+
+	s, _ := newSessionTest(reply)
+	cr, err := s.RunCommand("show foo", JunosFormatText)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cr.Output != "\nr1\n" {
+		t.Errorf("got output %q, expected %q", cr.Output, "\nr1\n")
+	}
+	if len(cr.Warnings) != 1 || cr.Warnings[0].SourceDaemon != "mgd" {
+		t.Errorf("unexpected warnings %+v", cr.Warnings)
+	}
+}
+
+func TestSessionRunCommandXNMError(t *testing.T) {
+	reply := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<xnm:error xmlns="http://xml.juniper.net/xnm/1.1/xnm" xmlns:xnm="http://xml.juniper.net/xnm/1.1/xnm">
+<token>foo</token>
+<message>syntax error, expecting &lt;command&gt;</message>
+</xnm:error>
+</rpc-reply>`
+
+	s, _ := newSessionTest(reply)
+	_, err := s.RunCommand("show foo", JunosFormatText)
+	xe, ok := err.(*XNMError)
+	if !ok {
+		t.Fatalf("expected *XNMError, got %v", err)
+	}
+	if xe.Severity != "error" || xe.Token != "foo" {
+		t.Errorf("unexpected error %+v", xe)
+	}
+	if xe.Error() != "netconf junos [error] 'syntax error, expecting <command>'" {
+		t.Errorf("unexpected error string %q", xe.Error())
+	}
+}
+
+func TestSessionRunCommandContext(t *testing.T) {
+	s, _ := newSessionTest()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.RunCommandContext(ctx, "show version", JunosFormatText); err != context.Canceled {
+		t.Errorf("got error %v, expected %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,400 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSessionClosed is returned by the operations of a closed ManagedSession.
+var ErrSessionClosed = errors.New("netconf: session closed")
+
+// SessionState is the connection state of a ManagedSession.
+type SessionState int
+
+// States of a ManagedSession.
+const (
+	SessionDisconnected SessionState = iota
+	SessionConnecting
+	SessionConnected
+	SessionClosed
+)
+
+func (s SessionState) String() string {
+	switch s {
+	case SessionDisconnected:
+		return "disconnected"
+	case SessionConnecting:
+		return "connecting"
+	case SessionConnected:
+		return "connected"
+	case SessionClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("SessionState(%d)", int(s))
+}
+
+// ManagedSession is a NETCONF session re-dialed transparently on transport
+// failure, e.g. when the device reboots.  Subscriptions are re-established
+// on each new session and idempotent RPCs interrupted by a failure are
+// replayed, while other RPCs such as edit-config or commit fail with the
+// transport error since they may have been applied.
+//
+// Exec, Subscribe and Receive must not be called concurrently, while State
+// and Close can be called at any time.
+type ManagedSession struct {
+	// MinBackoff is the delay before the first re-dial, doubled after each
+	// failed attempt up to MaxBackoff.  They default to 1 second and 1
+	// minute.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// OnStateChange is called on every state change with the error causing
+	// it, if any.
+	OnStateChange func(state SessionState, err error)
+
+	dial   func(ctx context.Context) (*Session, error)
+	closed chan struct{}
+
+	mu            sync.Mutex
+	session       *Session
+	state         SessionState
+	subscriptions []RPCMethod
+}
+
+// NewManagedSession creates a ManagedSession opening its sessions with dial.
+// No session is dialed until the first operation or Connect.
+func NewManagedSession(dial func(ctx context.Context) (*Session, error)) *ManagedSession {
+	return &ManagedSession{dial: dial, closed: make(chan struct{})}
+}
+
+// State returns the current connection state.
+func (m *ManagedSession) State() SessionState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// Connect dials a session unless already connected, retrying with backoff
+// until ctx is done.
+func (m *ManagedSession) Connect(ctx context.Context) error {
+	_, err := m.connect(ctx)
+	return err
+}
+
+// Exec executes an RPC method or methods on the current session, dialing a
+// new one when needed.  Idempotent RPCs failing with a transport error are
+// replayed on a new session, see IsIdempotent.
+func (m *ManagedSession) Exec(ctx context.Context, methods ...RPCMethod) (*RPCReply, error) {
+	replay := IsIdempotent(methods...)
+	for {
+		s, err := m.connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		reply, err := s.ExecContext(ctx, methods...)
+		if err == nil || !m.failed(ctx, s, err) {
+			return reply, err
+		}
+		if !replay || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+}
+
+// Subscribe executes a subscription RPC, such as MethodCreateSubscription,
+// and records it to be executed again on every new session.
+func (m *ManagedSession) Subscribe(ctx context.Context, method RPCMethod) error {
+	for {
+		s, err := m.connect(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.ExecContext(ctx, method)
+		if err == nil {
+			m.mu.Lock()
+			m.subscriptions = append(m.subscriptions, method)
+			m.mu.Unlock()
+			return nil
+		}
+		if !m.failed(ctx, s, err) || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// Receive returns the next message received on the session, such as a
+// notification of a subscription.  When the transport fails, a new session
+// is dialed and the subscriptions are re-established before receiving again.
+func (m *ManagedSession) Receive(ctx context.Context) ([]byte, error) {
+	for {
+		s, err := m.connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := receiveContext(ctx, s.Transport)
+		if err == nil || !m.failed(ctx, s, err) || ctx.Err() != nil {
+			return msg, err
+		}
+	}
+}
+
+// Close closes the current session, making later operations fail with
+// ErrSessionClosed.
+func (m *ManagedSession) Close() error {
+	m.mu.Lock()
+	if m.state == SessionClosed {
+		m.mu.Unlock()
+		return nil
+	}
+	s := m.session
+	m.session = nil
+	m.state = SessionClosed
+	close(m.closed)
+	m.mu.Unlock()
+
+	m.notify(SessionClosed, nil)
+	if s != nil {
+		return s.Close()
+	}
+	return nil
+}
+
+// connect returns the current session or dials a new one, retrying with
+// backoff
+func (m *ManagedSession) connect(ctx context.Context) (*Session, error) {
+	m.mu.Lock()
+	switch {
+	case m.state == SessionClosed:
+		m.mu.Unlock()
+		return nil, ErrSessionClosed
+	case m.session != nil && transportErr(m.session.Transport) == nil:
+		s := m.session
+		m.mu.Unlock()
+		return s, nil
+	}
+	dead := m.session
+	m.session = nil
+	m.mu.Unlock()
+
+	if dead != nil {
+		m.disconnected(dead, transportErr(dead.Transport))
+	}
+
+	backoff := m.MinBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	maxBackoff := m.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = time.Minute
+	}
+
+	for {
+		if !m.setState(SessionConnecting, nil) {
+			return nil, ErrSessionClosed
+		}
+
+		s, err := m.dialSession(ctx)
+		if err == nil {
+			m.mu.Lock()
+			if m.state == SessionClosed {
+				m.mu.Unlock()
+				s.Close()
+				return nil, ErrSessionClosed
+			}
+			m.session = s
+			m.state = SessionConnected
+			m.mu.Unlock()
+
+			m.notify(SessionConnected, nil)
+			return s, nil
+		}
+
+		if !m.setState(SessionDisconnected, err) {
+			return nil, ErrSessionClosed
+		}
+		// Devices fail in many ways while rebooting so any dial error is
+		// retried, unlike errors returned by the server
+		switch err.(type) {
+		case *RPCError, *XNMError:
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-m.closed:
+			return nil, ErrSessionClosed
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// dialSession dials a new session and re-establishes the subscriptions
+func (m *ManagedSession) dialSession(ctx context.Context) (*Session, error) {
+	s, err := m.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// NewSession does not report hello failures, a session without
+	// capabilities never received the hello of the server
+	if len(s.ServerCapabilities) == 0 {
+		s.Close()
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	m.mu.Lock()
+	subscriptions := append([]RPCMethod(nil), m.subscriptions...)
+	m.mu.Unlock()
+
+	for _, method := range subscriptions {
+		if _, err := s.ExecContext(ctx, method); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// failed handles the failure of an operation on s, reporting whether the
+// session was lost
+func (m *ManagedSession) failed(ctx context.Context, s *Session, err error) bool {
+	// The transport is closed when ctx is done during an operation
+	if !IsTransportError(err) && ctx.Err() == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	current := m.session == s
+	if current {
+		m.session = nil
+	}
+	m.mu.Unlock()
+
+	if current {
+		m.disconnected(s, err)
+	}
+	return true
+}
+
+func (m *ManagedSession) disconnected(s *Session, err error) {
+	s.Close()
+	m.setState(SessionDisconnected, err)
+}
+
+// setState changes the state unless the session is closed
+func (m *ManagedSession) setState(state SessionState, err error) bool {
+	m.mu.Lock()
+	if m.state == SessionClosed {
+		m.mu.Unlock()
+		return false
+	}
+	changed := m.state != state
+	m.state = state
+	m.mu.Unlock()
+
+	if changed {
+		m.notify(state, err)
+	}
+	return true
+}
+
+func (m *ManagedSession) notify(state SessionState, err error) {
+	if m.OnStateChange != nil {
+		m.OnStateChange(state, err)
+	}
+}
+
+// IsTransportError reports whether err is a failure of the transport rather
+// than an error returned by the server, meaning the session is lost.
+func IsTransportError(err error) bool {
+	switch err := err.(type) {
+	case nil, *RPCError, *XNMError:
+		return false
+	case *KeepaliveError:
+		return true
+	case net.Error:
+		return true
+	default:
+		return err == io.EOF || err == io.ErrUnexpectedEOF || err == io.ErrClosedPipe ||
+			err == ErrSSHConnectionClosed || err == ErrWaitForFunc
+	}
+}
+
+// IsIdempotent reports whether the methods only retrieve data and can safely
+// be executed again: get, get-config, get-schema and the other get-* RPCs
+// such as the Junos get-*-information ones.
+func IsIdempotent(methods ...RPCMethod) bool {
+	for _, method := range methods {
+		name := rpcName(method.MarshalMethod())
+		if name != "get" && !strings.HasPrefix(name, "get-") {
+			return false
+		}
+	}
+	return len(methods) > 0
+}
+
+// rpcName returns the name of the first element of a marshaled method
+func rpcName(method string) string {
+	d := xml.NewDecoder(strings.NewReader(method))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local
+		}
+	}
+}
+
+// transportErr returns the error marking the transport dead, if any
+func transportErr(t Transport) error {
+	if t, ok := t.(interface{ Err() error }); ok {
+		return t.Err()
+	}
+	return nil
+}
+
+// receiveContext receives a message from t, closing t when ctx is done
+func receiveContext(ctx context.Context, t Transport) ([]byte, error) {
+	if ctx.Done() == nil {
+		return t.Receive()
+	}
+
+	type result struct {
+		msg []byte
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		msg, err := t.Receive()
+		done <- result{msg, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.msg, res.err
+	case <-ctx.Done():
+		t.Close()
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,226 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testOkReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>`
+
+// managedSessionTest dials test sessions receiving the given replies before
+// failing with io.EOF, recording the state changes
+type managedSessionTest struct {
+	replies [][]string
+	dialErr []error
+	outs    []*bytes.Buffer
+	states  []SessionState
+}
+
+func (mt *managedSessionTest) dial(ctx context.Context) (*Session, error) {
+	if len(mt.dialErr) > 0 {
+		err := mt.dialErr[0]
+		mt.dialErr = mt.dialErr[1:]
+		return nil, err
+	}
+
+	var replies []string
+	if len(mt.replies) > 0 {
+		replies = mt.replies[0]
+		mt.replies = mt.replies[1:]
+	}
+	s, out := newSessionTest(replies...)
+	mt.outs = append(mt.outs, out)
+	return s, nil
+}
+
+func (mt *managedSessionTest) session() *ManagedSession {
+	m := NewManagedSession(mt.dial)
+	m.MinBackoff = time.Millisecond
+	m.OnStateChange = func(state SessionState, err error) {
+		mt.states = append(mt.states, state)
+	}
+	return m
+}
+
+func TestManagedSessionReplay(t *testing.T) {
+	mt := &managedSessionTest{replies: [][]string{{testDataReply}, {testDataReply}}}
+	m := mt.session()
+	defer m.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := m.Exec(ctx, MethodGetConfig("running")); err != nil {
+			t.Fatalf("exec %d: unexpected error: %v", i, err)
+		}
+	}
+
+	if len(mt.outs) != 2 {
+		t.Errorf("got %d dials, expected 2", len(mt.outs))
+	}
+	expected := []SessionState{SessionConnecting, SessionConnected, SessionDisconnected, SessionConnecting, SessionConnected}
+	if !cmp.Equal(mt.states, expected) {
+		t.Errorf("unexpected states:\n%s", cmp.Diff(expected, mt.states))
+	}
+}
+
+func TestManagedSessionNoReplay(t *testing.T) {
+	mt := &managedSessionTest{replies: [][]string{nil, {testOkReply}}}
+	m := mt.session()
+	defer m.Close()
+
+	ctx := context.Background()
+	edit := MethodEditConfig("candidate", "<system/>")
+	if _, err := m.Exec(ctx, edit); err != io.EOF {
+		t.Errorf("got error %v, expected %v", err, io.EOF)
+	}
+	if len(mt.outs) != 1 {
+		t.Errorf("got %d dials, expected 1", len(mt.outs))
+	}
+	if m.State() != SessionDisconnected {
+		t.Errorf("got state %s, expected %s", m.State(), SessionDisconnected)
+	}
+
+	// The next call dials a new session
+	if _, err := m.Exec(ctx, edit); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(mt.outs) != 2 {
+		t.Errorf("got %d dials, expected 2", len(mt.outs))
+	}
+}
+
+func TestManagedSessionSubscribe(t *testing.T) {
+	notification := `<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><eventTime>%d</eventTime></notification>`
+	mt := &managedSessionTest{replies: [][]string{
+		{testOkReply, fmt.Sprintf(notification, 1)},
+		{testOkReply, fmt.Sprintf(notification, 2)},
+	}}
+	m := mt.session()
+	defer m.Close()
+
+	ctx := context.Background()
+	if err := m.Subscribe(ctx, MethodCreateSubscription("")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		msg, err := m.Receive(ctx)
+		if err != nil {
+			t.Fatalf("receive %d: unexpected error: %v", i, err)
+		}
+		if expected := fmt.Sprintf(notification, i); string(msg) != expected {
+			t.Errorf("got %s, expected %s", msg, expected)
+		}
+	}
+
+	if len(mt.outs) != 2 {
+		t.Fatalf("got %d dials, expected 2", len(mt.outs))
+	}
+	if !strings.Contains(mt.outs[1].String(), "<create-subscription") {
+		t.Errorf("subscription not re-established: %s", mt.outs[1])
+	}
+}
+
+func TestManagedSessionBackoff(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	mt := &managedSessionTest{
+		dialErr: []error{refused, refused},
+		replies: [][]string{{testOkReply}},
+	}
+	m := mt.session()
+	defer m.Close()
+
+	if err := m.Connect(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []SessionState{SessionConnecting, SessionDisconnected, SessionConnecting, SessionDisconnected, SessionConnecting, SessionConnected}
+	if !cmp.Equal(mt.states, expected) {
+		t.Errorf("unexpected states:\n%s", cmp.Diff(expected, mt.states))
+	}
+
+	// Dialing gives up when ctx is done
+	mt = &managedSessionTest{dialErr: []error{refused, refused, refused}}
+	m = mt.session()
+	m.MinBackoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.Connect(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestManagedSessionClose(t *testing.T) {
+	mt := &managedSessionTest{}
+	m := mt.session()
+
+	if err := m.Connect(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.Close()
+	if m.State() != SessionClosed {
+		t.Errorf("got state %s, expected %s", m.State(), SessionClosed)
+	}
+	if _, err := m.Exec(context.Background(), MethodGetConfig("running")); err != ErrSessionClosed {
+		t.Errorf("got error %v, expected %v", err, ErrSessionClosed)
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	tt := []struct {
+		methods  []RPCMethod
+		expected bool
+	}{
+		{[]RPCMethod{MethodGetConfig("running")}, true},
+		{[]RPCMethod{MethodGet("subtree", "<system/>")}, true},
+		{[]RPCMethod{RawMethod("<get-schema><identifier>foo</identifier></get-schema>")}, true},
+		{[]RPCMethod{RawMethod("<get-interface-information/>")}, true},
+		{[]RPCMethod{MethodGetConfig("running"), MethodEditConfig("candidate", "<system/>")}, false},
+		{[]RPCMethod{MethodCommitConfiguration(CommitOptions{})}, false},
+		{[]RPCMethod{MethodLock("candidate")}, false},
+		{nil, false},
+	}
+
+	for _, tc := range tt {
+		if got := IsIdempotent(tc.methods...); got != tc.expected {
+			t.Errorf("IsIdempotent(%v) = %t, expected %t", tc.methods, got, tc.expected)
+		}
+	}
+}
+
+func TestIsTransportError(t *testing.T) {
+	tt := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{io.EOF, true},
+		{ErrWaitForFunc, true},
+		{errors.New("WaitForFunc failed"), false},
+		{&KeepaliveError{Missed: 3}, true},
+		{&net.OpError{Op: "read", Err: errors.New("connection reset")}, true},
+		{&RPCError{Severity: "error", Message: "lock denied"}, false},
+		{&XNMError{Severity: "error", Message: "syntax error"}, false},
+		{context.Canceled, false},
+	}
+
+	for _, tc := range tt {
+		if got := IsTransportError(tc.err); got != tc.expected {
+			t.Errorf("IsTransportError(%v) = %t, expected %t", tc.err, got, tc.expected)
+		}
+	}
+}
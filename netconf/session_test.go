@@ -0,0 +1,174 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testServerHello = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+  <capabilities>
+    <capability>urn:ietf:params:netconf:base:1.0</capability>
+  </capabilities>
+  <session-id>42</session-id>
+</hello>`
+
+// messageReader returns at most one framed message per Read, as the
+// transport discards anything read past the message separator.
+type messageReader struct {
+	messages []string
+}
+
+func (r *messageReader) Read(b []byte) (int, error) {
+	if len(r.messages) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(b, r.messages[0])
+	r.messages[0] = r.messages[0][n:]
+	if r.messages[0] == "" {
+		r.messages = r.messages[1:]
+	}
+	return n, nil
+}
+
+// newSessionTest returns a session that receives a base:1.0 hello followed by
+// the given replies.  Everything sent by the session is written to the
+// returned buffer.
+func newSessionTest(replies ...string) (*Session, *bytes.Buffer) {
+	r := &messageReader{messages: []string{testServerHello + msgSeperator}}
+	for _, reply := range replies {
+		r.messages = append(r.messages, reply+msgSeperator)
+	}
+
+	out := new(bytes.Buffer)
+	trans := &transportTest{}
+	trans.ReadWriteCloser = newNilCloser(r, out)
+
+	s := NewSession(trans)
+	out.Reset()
+	return s, out
+}
+
+func TestSessionExec(t *testing.T) {
+	s, out := newSessionTest(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>`)
+
+	if s.SessionID != 42 {
+		t.Errorf("got session-id %d, expected 42", s.SessionID)
+	}
+
+	reply, err := s.Exec(MethodLock("candidate"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reply.MessageID == "" {
+		t.Errorf("reply message-id is not set")
+	}
+	if !strings.Contains(out.String(), "<lock><target><candidate/></target></lock>") {
+		t.Errorf("unexpected request %q", out.String())
+	}
+}
+
+func TestSessionExecErrorReply(t *testing.T) {
+	s, _ := newSessionTest(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<rpc-error><error-type>application</error-type><error-tag>lock-denied</error-tag><error-severity>error</error-severity><error-message>locked</error-message></rpc-error>
+</rpc-reply>`)
+
+	reply, err := s.Exec(MethodLock("candidate"))
+	if _, ok := err.(*RPCError); !ok {
+		t.Fatalf("expected *RPCError, got %v", err)
+	}
+	if reply == nil || len(reply.Errors) != 1 || reply.Errors[0].Tag != "lock-denied" {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+}
+
+func TestSessionExecContextCancel(t *testing.T) {
+	s, _ := newSessionTest()
+
+	// Replace the transport input with one that never replies
+	r, w := io.Pipe()
+	defer w.Close()
+	s.Transport.(*transportTest).ReadWriteCloser = newNilCloser(r, ioutil.Discard)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := s.ExecContext(ctx, MethodGetConfig("running"))
+	if err != context.DeadlineExceeded {
+		t.Errorf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
+
+const testDataReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<data>
+<system xmlns="urn:example:system"><host-name>r1</host-name></system>
+</data>
+</rpc-reply>`
+
+type testSystem struct {
+	HostName string `xml:"system>host-name"`
+}
+
+func TestSessionGet(t *testing.T) {
+	s, out := newSessionTest(testDataReply)
+
+	var v testSystem
+	if err := s.Get(context.Background(), `<system xmlns="urn:example:system"/>`, &v); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.HostName != "r1" {
+		t.Errorf("got host-name %q, expected r1", v.HostName)
+	}
+	if !strings.Contains(out.String(), `<get><filter type="subtree"><system xmlns="urn:example:system"/></filter></get>`) {
+		t.Errorf("unexpected request %q", out.String())
+	}
+}
+
+func TestSessionGetConfig(t *testing.T) {
+	tt := []struct {
+		name    string
+		filter  string
+		reply   string
+		request string
+	}{
+		{
+			name:    "data",
+			reply:   testDataReply,
+			request: "<get-config><source><running/></source></get-config>",
+		},
+		{
+			name:    "junos",
+			filter:  "<configuration><system/></configuration>",
+			reply:   `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><system><host-name>r1</host-name></system></rpc-reply>`,
+			request: `<get-config><source><running/></source><filter type="subtree"><configuration><system/></configuration></filter></get-config>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			s, out := newSessionTest(tc.reply)
+
+			var v testSystem
+			if err := s.GetConfig(context.Background(), "running", tc.filter, &v); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v.HostName != "r1" {
+				t.Errorf("got host-name %q, expected r1", v.HostName)
+			}
+			if !strings.Contains(out.String(), tc.request) {
+				t.Errorf("unexpected request %q", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+)
+
+// Names of the spans created.
+const (
+	// SpanDial covers dialing a target up to the end of the hello exchange
+	SpanDial = "netconf.dial"
+	// SpanHello covers the hello exchange
+	SpanHello = "netconf.hello"
+	// SpanRPC covers an rpc, from sending it to receiving its reply
+	SpanRPC = "netconf.rpc"
+)
+
+// Keys of the attributes set on spans.
+const (
+	AttrTarget    = "netconf.target"
+	AttrSessionID = "netconf.session_id"
+	AttrOperation = "netconf.operation"
+	AttrMessageID = "netconf.message_id"
+	// AttrErrorTag is the error-tag of the first rpc-error of the reply
+	AttrErrorTag = "netconf.error_tag"
+)
+
+// Attribute is a key and value set on a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span is an operation traced, ended once the operation is done.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer creates spans as children of the span of ctx, if any, and returns
+// the context of the new span.  Adapters for OpenTelemetry or other tracing
+// libraries implement it.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// DefaultTracer sets the tracer of new sessions and dials, none when nil.
+var DefaultTracer Tracer
+
+// nopSpan is the span used when there is no tracer
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(attrs ...Attribute) {}
+func (nopSpan) RecordError(err error)            {}
+func (nopSpan) End()                             {}
+
+// startSpan starts a span with tracer, or a nopSpan when tracer is nil
+func startSpan(ctx context.Context, tracer Tracer, name string, attrs ...Attribute) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, nopSpan{}
+	}
+	return tracer.Start(ctx, name, attrs...)
+}
+
+// endSpan ends span, recording err if any
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// endRPCSpan ends the span of an rpc, setting the error-tag of its reply
+func endRPCSpan(span Span, reply *RPCReply, err error) {
+	if reply != nil && len(reply.Errors) > 0 {
+		span.SetAttributes(Attribute{AttrErrorTag, reply.Errors[0].Tag})
+	}
+	endSpan(span, err)
+}
+
+// operationName returns the name of the first operation of methods
+func operationName(methods []RPCMethod) string {
+	if len(methods) == 0 {
+		return ""
+	}
+	return rpcName(methods[0].MarshalMethod())
+}
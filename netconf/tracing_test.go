@@ -0,0 +1,173 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/crypto/ssh"
+)
+
+// spanTest records the attributes and error of a span
+type spanTest struct {
+	name   string
+	parent string
+	attrs  map[string]interface{}
+	err    string
+	ended  bool
+}
+
+func (s *spanTest) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *spanTest) RecordError(err error) {
+	s.err = err.Error()
+}
+
+func (s *spanTest) End() {
+	s.ended = true
+}
+
+type spanKey struct{}
+
+// tracerTest records the spans started
+type tracerTest struct {
+	mu    sync.Mutex
+	spans []*spanTest
+}
+
+func (tr *tracerTest) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	s := &spanTest{name: name, attrs: make(map[string]interface{})}
+	if parent, ok := ctx.Value(spanKey{}).(*spanTest); ok {
+		s.parent = parent.name
+	}
+	s.SetAttributes(attrs...)
+
+	tr.mu.Lock()
+	tr.spans = append(tr.spans, s)
+	tr.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+var spanTestComparer = cmp.AllowUnexported(spanTest{})
+
+func TestSessionTracing(t *testing.T) {
+	const errorReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><rpc-error>
+<error-type>protocol</error-type>
+<error-tag>lock-denied</error-tag>
+<error-severity>error</error-severity>
+<error-message>locked</error-message>
+</rpc-error></rpc-reply>`
+
+	tr := new(tracerTest)
+	defer func(tracer Tracer) { DefaultTracer = tracer }(DefaultTracer)
+	DefaultTracer = tr
+
+	ctx, _ := tr.Start(context.Background(), "caller")
+	tr.spans = nil
+
+	s, _ := newSessionTest(testOkReply, errorReply)
+	ok, err := s.ExecContext(ctx, MethodLock("candidate"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	failed, err := s.ExecContext(ctx, MethodLock("candidate"))
+	if err == nil {
+		t.Fatalf("expected rpc-error")
+	}
+
+	expected := []*spanTest{
+		{
+			name:  SpanHello,
+			attrs: map[string]interface{}{AttrSessionID: 42},
+			ended: true,
+		},
+		{
+			name:   SpanRPC,
+			parent: "caller",
+			attrs: map[string]interface{}{
+				AttrSessionID: 42,
+				AttrOperation: "lock",
+				AttrMessageID: ok.MessageID,
+			},
+			ended: true,
+		},
+		{
+			name:   SpanRPC,
+			parent: "caller",
+			attrs: map[string]interface{}{
+				AttrSessionID: 42,
+				AttrOperation: "lock",
+				AttrMessageID: failed.MessageID,
+				AttrErrorTag:  "lock-denied",
+			},
+			err:   "netconf rpc [error] 'locked'",
+			ended: true,
+		},
+	}
+	if diff := cmp.Diff(expected, tr.spans, spanTestComparer); diff != "" {
+		t.Errorf("spans mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDialSSHContextTracing(t *testing.T) {
+	server := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer server.Close()
+
+	tr := new(tracerTest)
+	defer func(tracer Tracer) { DefaultTracer = tracer }(DefaultTracer)
+	DefaultTracer = tr
+
+	s, err := DialSSHContext(context.Background(), server.Addr(), SSHConfigPassword("test", ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.Close()
+
+	if len(tr.spans) != 2 {
+		t.Fatalf("got %d spans, expected 2", len(tr.spans))
+	}
+	dial, hello := tr.spans[0], tr.spans[1]
+	if dial.name != SpanDial || dial.attrs[AttrTarget] != server.Addr() || dial.err != "" || !dial.ended {
+		t.Errorf("unexpected dial span %+v", dial)
+	}
+	if hello.name != SpanHello || hello.parent != SpanDial || !hello.ended {
+		t.Errorf("unexpected hello span %+v", hello)
+	}
+}
+
+// cancelTracer starts spans whose context is already canceled
+type cancelTracer struct {
+	tracerTest
+}
+
+func (tr *cancelTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	ctx, span := tr.tracerTest.Start(ctx, name, attrs...)
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	return ctx, span
+}
+
+func TestSessionTracingContext(t *testing.T) {
+	s, _ := newSessionTest(testOkReply)
+	tr := new(cancelTracer)
+	s.Tracer = tr
+
+	// The rpc runs in the context of its span
+	if _, err := s.ExecContext(context.Background(), MethodLock("candidate")); err != context.Canceled {
+		t.Errorf("got error %v, expected %v", err, context.Canceled)
+	}
+	if len(tr.spans) != 1 || tr.spans[0].err != context.Canceled.Error() || !tr.spans[0].ended {
+		t.Errorf("unexpected spans: %+v", tr.spans)
+	}
+}
@@ -8,10 +8,15 @@ package netconf
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"regexp"
+	"sync"
+	"time"
 )
 
 const (
@@ -20,6 +25,10 @@ const (
 	msgSeperator_v11 = "\n##\n"
 )
 
+// ErrWaitForFunc is returned when the transport input ends before the end of
+// the message being received.
+var ErrWaitForFunc = errors.New("WaitForFunc failed")
+
 // DefaultCapabilities sets the default capabilities of the client library
 var DefaultCapabilities = []string{
 	"urn:ietf:params:netconf:base:1.0",
@@ -134,6 +143,9 @@ func (t *TransportBasicIO) WaitForFunc(f func([]byte) (int, error)) ([]byte, err
 			// the end of the message
 			if n == 0 {
 				out.Write(buf[0:pos])
+				if out.Len() == 0 {
+					return nil, io.EOF
+				}
 				return out.Bytes(), nil
 			}
 			break
@@ -159,7 +171,7 @@ func (t *TransportBasicIO) WaitForFunc(f func([]byte) (int, error)) ([]byte, err
 		}
 	}
 
-	return nil, fmt.Errorf("WaitForFunc failed")
+	return nil, ErrWaitForFunc
 }
 
 func (t *TransportBasicIO) WaitForBytes(b []byte) ([]byte, error) {
@@ -202,3 +214,29 @@ type ReadWriteCloser struct {
 func NewReadWriteCloser(r io.Reader, w io.WriteCloser) *ReadWriteCloser {
 	return &ReadWriteCloser{r, w}
 }
+
+// watchDeadline expires the deadline of conn when ctx is done, failing the
+// pending reads and writes of a dial.  The returned function stops watching
+// ctx, waits for the watch to exit and clears the deadline so that it does
+// not outlive the dial.  It may be called more than once.
+func watchDeadline(ctx context.Context, conn net.Conn) (stop func()) {
+	stopc := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-stopc:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stopc)
+			<-done
+			conn.SetDeadline(time.Time{})
+		})
+	}
+}
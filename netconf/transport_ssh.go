@@ -7,13 +7,16 @@
 package netconf
 
 import (
-	"crypto/x509"
+	"bytes"
+	"context"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -35,6 +38,22 @@ type TransportSSH struct {
 	sshClient  *ssh.Client
 	sshSession *ssh.Session
 
+	// Clients of the jump hosts the connection is tunneled through, from
+	// the first hop to the last
+	jumpClients []*ssh.Client
+	// Resources such as SSH agents released with the transport
+	closers []io.Closer
+	// Agent forwarded to the remote device when set
+	agentForward agent.Agent
+	// Shared connection the session channel was opened on
+	conn *SSHConnection
+
+	// mu guards the keepalive state
+	mu        sync.Mutex
+	keepalive *sshKeepalive
+	// err is set when the keepalive found the connection dead
+	err error
+
 	// SSH Client connection is managed externally
 	managedSession bool
 }
@@ -45,6 +64,8 @@ func (t *TransportSSH) Close() error {
 	if t == nil {
 		return nil
 	}
+	t.StopKeepalive()
+	defer t.closeResources()
 
 	// Close the SSH Session if we have one
 	if t.sshSession != nil {
@@ -62,9 +83,35 @@ func (t *TransportSSH) Close() error {
 	if !t.managedSession && t.sshClient != nil {
 		return t.sshClient.Close()
 	}
+	if t.conn != nil {
+		return nil
+	}
 	return fmt.Errorf("No connection to close")
 }
 
+// closeResources closes the jump host clients, starting with the last hop,
+// and the resources owned by the transport, then releases the shared
+// connection
+func (t *TransportSSH) closeResources() {
+	for i := len(t.jumpClients) - 1; i >= 0; i-- {
+		t.jumpClients[i].Close()
+	}
+	t.jumpClients = nil
+
+	for _, c := range t.closers {
+		c.Close()
+	}
+	t.closers = nil
+
+	t.mu.Lock()
+	conn := t.conn
+	t.conn = nil
+	t.mu.Unlock()
+	if conn != nil {
+		conn.release()
+	}
+}
+
 // Dial connects and establishes SSH sessions
 //
 // target can be an IP address (e.g.) 172.16.1.1 which utlizes the default
@@ -86,8 +133,12 @@ func (t *TransportSSH) Dial(target string, config *ssh.ClientConfig) error {
 		return err
 	}
 
-	err = t.setupSession()
-	return err
+	if err := t.setupSession(); err != nil {
+		return err
+	}
+
+	t.StartKeepalive(DefaultSSHKeepalive)
+	return nil
 }
 
 func (t *TransportSSH) setupSession() error {
@@ -108,6 +159,15 @@ func (t *TransportSSH) setupSession() error {
 		return err
 	}
 
+	if t.agentForward != nil {
+		if err := agent.ForwardToAgent(t.sshClient, t.agentForward); err != nil {
+			return err
+		}
+		if err := agent.RequestAgentForwarding(t.sshSession); err != nil {
+			return err
+		}
+	}
+
 	t.ReadWriteCloser = NewReadWriteCloser(reader, writer)
 	return t.sshSession.RequestSubsystem(sshNetconfSubsystem)
 }
@@ -116,6 +176,7 @@ func (t *TransportSSH) setupSession() error {
 func NewSSHSession(conn net.Conn, config *ssh.ClientConfig) (*Session, error) {
 	t, err := connToTransport(conn, config)
 	if err != nil {
+		sessionFailed(err)
 		return nil, err
 	}
 
@@ -123,7 +184,8 @@ func NewSSHSession(conn net.Conn, config *ssh.ClientConfig) (*Session, error) {
 }
 
 // NewSSHClientSession creates a new NETCONF session using an existing ssh.Client
-// initiated and managed externally.
+// initiated and managed externally.  See SSHConnection to share a client
+// between sessions closed with the last one.
 func NewSSHClientSession(client *ssh.Client) (*Session, error) {
 	t := &TransportSSH{
 		sshClient:      client,
@@ -132,6 +194,7 @@ func NewSSHClientSession(client *ssh.Client) (*Session, error) {
 
 	err := t.setupSession()
 	if err != nil {
+		sessionFailed(err)
 		return nil, err
 	}
 
@@ -140,22 +203,137 @@ func NewSSHClientSession(client *ssh.Client) (*Session, error) {
 
 // DialSSH creates a new NETCONF session using a SSH Transport.
 // See TransportSSH.Dial for arguments.
-func DialSSH(target string, config *ssh.ClientConfig) (*Session, error) {
+func DialSSH(target string, config *ssh.ClientConfig) (s *Session, err error) {
+	ctx, span := startSpan(context.Background(), DefaultTracer, SpanDial, Attribute{AttrTarget, target})
+	defer func() { endSpan(span, err) }()
+
 	var t TransportSSH
-	err := t.Dial(target, config)
+	err = t.Dial(target, config)
 	if err != nil {
 		t.Close()
+		sessionFailed(err)
+		return nil, err
+	}
+	return NewSessionContext(ctx, &t), nil
+}
+
+// DialSSHContext creates a new NETCONF session using a SSH Transport, giving
+// up when ctx is done before the session is established.  The port defaults
+// to 830 when target has none.  The dial is traced as a child of ctx.
+func DialSSHContext(ctx context.Context, target string, config *ssh.ClientConfig) (s *Session, err error) {
+	ctx, span := startSpan(ctx, DefaultTracer, SpanDial, Attribute{AttrTarget, target})
+	defer func() { endSpan(span, err) }()
+
+	if !strings.Contains(target, ":") {
+		target = fmt.Sprintf("%s:%d", target, sshDefaultPort)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", target)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		sessionFailed(err)
+		return nil, err
+	}
+
+	// Bound the handshake and hello exchange by ctx
+	stop := watchDeadline(ctx, conn)
+	defer stop()
+
+	t, err := connAddrToTransport(conn, target, config)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		sessionFailed(err)
 		return nil, err
 	}
-	return NewSession(&t), nil
+
+	s = NewSessionContext(ctx, t)
+	stop()
+	if ctx.Err() != nil {
+		s.Close()
+		return nil, ctx.Err()
+	}
+	return s, nil
+}
+
+// SSHJumpHost describes an intermediate SSH server the NETCONF connection is
+// tunneled through.
+type SSHJumpHost struct {
+	// Target is the address of the jump host, using port 22 when no port is
+	// given
+	Target string
+	Config *ssh.ClientConfig
+}
+
+// DialSSHJump creates a new NETCONF session using a SSH Transport tunneled
+// through a chain of jump hosts, the first one being dialed directly.  Each
+// following hop and the target are reached through direct-tcpip channels of
+// the previous hop.  The jump host connections are closed with the session.
+// See TransportSSH.Dial for target and config.
+func DialSSHJump(target string, config *ssh.ClientConfig, jumps ...SSHJumpHost) (*Session, error) {
+	if !strings.Contains(target, ":") {
+		target = fmt.Sprintf("%s:%d", target, sshDefaultPort)
+	}
+
+	var jumpClients []*ssh.Client
+	fail := func(err error) (*Session, error) {
+		for i := len(jumpClients) - 1; i >= 0; i-- {
+			jumpClients[i].Close()
+		}
+		sessionFailed(err)
+		return nil, err
+	}
+
+	dial := net.Dial
+	for _, jump := range jumps {
+		addr := jump.Target
+		if !strings.Contains(addr, ":") {
+			addr = fmt.Sprintf("%s:%d", addr, 22)
+		}
+
+		conn, err := dial("tcp", addr)
+		if err != nil {
+			return fail(err)
+		}
+
+		c, chans, reqs, err := ssh.NewClientConn(conn, addr, jump.Config)
+		if err != nil {
+			conn.Close()
+			return fail(err)
+		}
+		client := ssh.NewClient(c, chans, reqs)
+		jumpClients = append(jumpClients, client)
+		dial = client.Dial
+	}
+
+	conn, err := dial("tcp", target)
+	if err != nil {
+		return fail(err)
+	}
+
+	t, err := connAddrToTransport(conn, target, config)
+	if err != nil {
+		conn.Close()
+		return fail(err)
+	}
+	t.jumpClients = jumpClients
+
+	return NewSession(t), nil
 }
 
 // DialSSHTimeout creates a new NETCONF session using a SSH Transport with timeout.
 // See TransportSSH.Dial for arguments.
 // The timeout value is used for both connection establishment and Read/Write operations.
+// Keepalives are sent every timeout/2 so idle sessions do not time out.
 func DialSSHTimeout(target string, config *ssh.ClientConfig, timeout time.Duration) (*Session, error) {
 	bareConn, err := net.DialTimeout("tcp", target, timeout)
 	if err != nil {
+		sessionFailed(err)
 		return nil, err
 	}
 
@@ -165,19 +343,11 @@ func DialSSHTimeout(target string, config *ssh.ClientConfig, timeout time.Durati
 		if t != nil {
 			t.Close()
 		}
+		sessionFailed(err)
 		return nil, err
 	}
 
-	go func() {
-		ticker := time.NewTicker(timeout / 2)
-		defer ticker.Stop()
-		for range ticker.C {
-			_, _, err := t.sshClient.Conn.SendRequest("KEEP_ALIVE", true, nil)
-			if err != nil {
-				return
-			}
-		}
-	}()
+	t.StartKeepalive(SSHKeepalive{Interval: timeout / 2})
 
 	return NewSession(t), nil
 }
@@ -185,6 +355,10 @@ func DialSSHTimeout(target string, config *ssh.ClientConfig, timeout time.Durati
 // SSHConfigPassword is a convenience function that takes a username and password
 // and returns a new ssh.ClientConfig setup to pass that username and password.
 // Convenience means that HostKey checks are disabled so it's probably less secure
+//
+// Deprecated: any host can impersonate the device and capture the password.
+// Use SSHConfigPasswordKnownHosts, or set the HostKeyCallback of the returned
+// config, e.g. with SSHConfigKnownHosts.
 func SSHConfigPassword(user string, pass string) *ssh.ClientConfig {
 	return &ssh.ClientConfig{
 		User: user,
@@ -195,46 +369,127 @@ func SSHConfigPassword(user string, pass string) *ssh.ClientConfig {
 	}
 }
 
+// SSHConfigPasswordKnownHosts is a convenience function that takes a username
+// and password and returns a new ssh.ClientConfig setup to pass them to hosts
+// whose key is in the OpenSSH known_hosts files, ~/.ssh/known_hosts by
+// default.  See KnownHostsCallback.
+func SSHConfigPasswordKnownHosts(user string, pass string, files ...string) (*ssh.ClientConfig, error) {
+	config := &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(pass),
+		},
+	}
+	return SSHConfigKnownHosts(config, files...)
+}
+
 // SSHConfigPubKeyFile is a convenience function that takes a username, private key
 // and passphrase and returns a new ssh.ClientConfig setup to pass credentials
-// to DialSSH
+// to DialSSH.  See SSHSignersFromFile for the supported key formats.  The
+// HostKeyCallback is left unset and must be provided, e.g. with
+// SSHConfigKnownHosts.
 func SSHConfigPubKeyFile(user string, file string, passphrase string) (*ssh.ClientConfig, error) {
-	buf, err := ioutil.ReadFile(file)
+	signers, err := SSHSignersFromFile(file, passphrase)
 	if err != nil {
 		return nil, err
 	}
-	block, rest := pem.Decode(buf)
-	if len(rest) > 0 {
-		return nil, fmt.Errorf("pem: unable to decode file %s", file)
+	return &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(signers...),
+		},
+	}, nil
+
+}
+
+// SSHSignersFromFile returns signers for the private keys held in file,
+// decrypting them with passphrase when needed.  Keys can be in OpenSSH,
+// PKCS#1, SEC1 or PKCS#8 PEM format and a file can hold several keys.  When
+// an OpenSSH certificate file exists next to the key file (<file>-cert.pub),
+// certificate signers for the matching keys are returned first.
+func SSHSignersFromFile(file string, passphrase string) ([]ssh.Signer, error) {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
 	}
 
-	if x509.IsEncryptedPEMBlock(block) {
-		b, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+	var keys []ssh.Signer
+	for {
+		var block *pem.Block
+		block, buf = pem.Decode(buf)
+		if block == nil {
+			break
+		}
+
+		key, err := parsePrivateKey(pem.EncodeToMemory(block), passphrase)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%s: %v", file, err)
 		}
-		buf = pem.EncodeToMemory(&pem.Block{
-			Type:  block.Type,
-			Bytes: b,
-		})
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("pem: no private key found in %s", file)
 	}
 
-	key, err := ssh.ParsePrivateKey(buf)
+	certs, err := certSigners(file+"-cert.pub", keys)
 	if err != nil {
 		return nil, err
 	}
-	return &ssh.ClientConfig{
-		User: user,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(key),
-		},
-	}, nil
+	return append(certs, keys...), nil
+}
 
+// parsePrivateKey parses a PEM encoded private key, only using the
+// passphrase for encrypted keys
+func parsePrivateKey(pemBytes []byte, passphrase string) (ssh.Signer, error) {
+	key, err := ssh.ParsePrivateKey(pemBytes)
+	if _, ok := err.(*ssh.PassphraseMissingError); ok && passphrase != "" {
+		return ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+	}
+	return key, err
+}
+
+// certSigners returns signers for the certificates in file matching keys.  A
+// missing file is not an error.
+func certSigners(file string, keys []ssh.Signer) ([]ssh.Signer, error) {
+	buf, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var signers []ssh.Signer
+	for len(bytes.TrimSpace(buf)) > 0 {
+		pub, _, _, rest, err := ssh.ParseAuthorizedKey(buf)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		buf = rest
+
+		cert, ok := pub.(*ssh.Certificate)
+		if !ok {
+			continue
+		}
+		for _, key := range keys {
+			if !bytes.Equal(cert.Key.Marshal(), key.PublicKey().Marshal()) {
+				continue
+			}
+			signer, err := ssh.NewCertSigner(cert, key)
+			if err != nil {
+				return nil, err
+			}
+			signers = append(signers, signer)
+		}
+	}
+	return signers, nil
 }
 
 // SSHConfigPubKeyAgent is a convience function that takes a username and
 // returns a new ssh.Clientconfig setup to pass credentials received from
-// an ssh agent
+// an ssh agent.  The agent connection is never closed, long running programs
+// should use SSHAgent instead.  The HostKeyCallback is left unset and must be
+// provided, e.g. with SSHConfigKnownHosts.
 func SSHConfigPubKeyAgent(user string) (*ssh.ClientConfig, error) {
 	c, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK"))
 	if err != nil {
@@ -249,7 +504,13 @@ func SSHConfigPubKeyAgent(user string) (*ssh.ClientConfig, error) {
 }
 
 func connToTransport(conn net.Conn, config *ssh.ClientConfig) (*TransportSSH, error) {
-	c, chans, reqs, err := ssh.NewClientConn(conn, conn.RemoteAddr().String(), config)
+	return connAddrToTransport(conn, conn.RemoteAddr().String(), config)
+}
+
+// connAddrToTransport is connToTransport for connections whose remote address
+// is not the one host keys are verified against, such as tunneled ones.
+func connAddrToTransport(conn net.Conn, addr string, config *ssh.ClientConfig) (*TransportSSH, error) {
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
 	if err != nil {
 		return nil, err
 	}
@@ -262,6 +523,7 @@ func connToTransport(conn net.Conn, config *ssh.ClientConfig) (*TransportSSH, er
 		return nil, err
 	}
 
+	t.StartKeepalive(DefaultSSHKeepalive)
 	return t, nil
 }
 
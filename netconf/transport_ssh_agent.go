@@ -0,0 +1,143 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// SSHAgent is a connection to a SSH agent providing the keys used for public
+// key authentication.  It owns the connection to the agent, which is released
+// by Close or with the session when dialed with DialSSHAgent.
+type SSHAgent struct {
+	// Forward requests agent forwarding on the sessions dialed with
+	// DialSSHAgent, for devices that need the agent to reach other hosts
+	Forward bool
+
+	conn   net.Conn
+	client agent.ExtendedAgent
+	filter func(key *agent.Key) bool
+}
+
+// NewSSHAgent connects to the SSH agent listening on SSH_AUTH_SOCK.
+func NewSSHAgent() (*SSHAgent, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, fmt.Errorf("netconf ssh: SSH_AUTH_SOCK is not set")
+	}
+	return NewSSHAgentSocket(socket)
+}
+
+// NewSSHAgentSocket connects to the SSH agent listening on the unix socket.
+func NewSSHAgentSocket(socket string) (*SSHAgent, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	return &SSHAgent{conn: conn, client: agent.NewClient(conn)}, nil
+}
+
+// FilterComments restricts the keys offered to the ones with one of the
+// given comments, typically the key file name.
+func (a *SSHAgent) FilterComments(comments ...string) {
+	a.filter = func(key *agent.Key) bool {
+		for _, c := range comments {
+			if key.Comment == c {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FilterFingerprints restricts the keys offered to the ones with one of the
+// given fingerprints, in SHA256 ("SHA256:...") or legacy MD5 format.
+func (a *SSHAgent) FilterFingerprints(fingerprints ...string) {
+	a.filter = func(key *agent.Key) bool {
+		for _, fp := range fingerprints {
+			if fp == ssh.FingerprintSHA256(key) || fp == ssh.FingerprintLegacyMD5(key) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Signers returns signers for the agent keys passing the filter.
+func (a *SSHAgent) Signers() ([]ssh.Signer, error) {
+	signers, err := a.client.Signers()
+	if err != nil || a.filter == nil {
+		return signers, err
+	}
+
+	keys, err := a.client.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []ssh.Signer
+	for _, signer := range signers {
+		pub := signer.PublicKey().Marshal()
+		for _, key := range keys {
+			if bytes.Equal(pub, key.Marshal()) && a.filter(key) {
+				filtered = append(filtered, signer)
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
+
+// AuthMethod returns a public key authentication method using the agent.
+func (a *SSHAgent) AuthMethod() ssh.AuthMethod {
+	return ssh.PublicKeysCallback(a.Signers)
+}
+
+// Close closes the connection to the agent.
+func (a *SSHAgent) Close() error {
+	return a.conn.Close()
+}
+
+// SSHConfigAgent is a convenience function that takes a username and an agent
+// and returns a new ssh.ClientConfig authenticating with the agent keys.  The
+// HostKeyCallback is left unset and must be provided, e.g. with
+// SSHConfigKnownHosts.
+func SSHConfigAgent(user string, a *SSHAgent) *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{
+			a.AuthMethod(),
+		},
+	}
+}
+
+// DialSSHAgent creates a new NETCONF session using a SSH Transport which
+// takes ownership of the agent: it is closed with the session, or on error.
+// Agent forwarding is requested when a.Forward is set.  See TransportSSH.Dial
+// for arguments, config is expected to authenticate with the agent, e.g.
+// using SSHConfigAgent.
+func DialSSHAgent(target string, config *ssh.ClientConfig, a *SSHAgent) (*Session, error) {
+	var t TransportSSH
+	t.closers = append(t.closers, a)
+	if a.Forward {
+		t.agentForward = a.client
+	}
+
+	err := t.Dial(target, config)
+	if err != nil {
+		t.Close()
+		sessionFailed(err)
+		return nil, err
+	}
+	return NewSession(&t), nil
+}
@@ -0,0 +1,162 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// newTestAgent serves an in-memory agent holding keys with the given comments
+// on a unix socket and returns the socket path and the keys.
+func newTestAgent(t *testing.T, dir string, comments ...string) (string, []ssh.Signer) {
+	keyring := agent.NewKeyring()
+
+	var signers []ssh.Signer
+	for _, comment := range comments {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := keyring.Add(agent.AddedKey{PrivateKey: priv, Comment: comment}); err != nil {
+			t.Fatal(err)
+		}
+		signer, err := ssh.NewSignerFromKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, signer)
+	}
+
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				agent.ServeAgent(keyring, c)
+				c.Close()
+			}()
+		}
+	}()
+
+	return socket, signers
+}
+
+func TestSSHAgentFilters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket, keys := newTestAgent(t, dir, "work", "personal")
+	a, err := NewSSHAgentSocket(socket)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer a.Close()
+
+	tt := []struct {
+		name     string
+		filter   func()
+		expected []ssh.Signer
+	}{
+		{"none", func() {}, keys},
+		{"comment", func() { a.FilterComments("personal") }, keys[1:]},
+		{"sha256", func() { a.FilterFingerprints(ssh.FingerprintSHA256(keys[0].PublicKey())) }, keys[:1]},
+		{"md5", func() { a.FilterFingerprints(ssh.FingerprintLegacyMD5(keys[1].PublicKey())) }, keys[1:]},
+		{"noMatch", func() { a.FilterComments("other") }, nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.filter()
+			signers, err := a.Signers()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(signers) != len(tc.expected) {
+				t.Fatalf("got %d signers, expected %d", len(signers), len(tc.expected))
+			}
+			for i := range signers {
+				if !bytes.Equal(signers[i].PublicKey().Marshal(), tc.expected[i].PublicKey().Marshal()) {
+					t.Errorf("signer %d does not match the expected key", i)
+				}
+			}
+		})
+	}
+}
+
+func TestDialSSHAgent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket, keys := newTestAgent(t, dir, "work", "personal")
+	server := newTestSSHServer(t, &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), keys[1].PublicKey().Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key")
+		},
+	})
+	defer server.Close()
+
+	a, err := NewSSHAgentSocket(socket)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.FilterComments("personal")
+	a.Forward = true
+
+	config := SSHConfigAgent("test", a)
+	config.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+
+	s, err := DialSSHAgent(server.Addr(), config, a)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case forwarded := <-server.agentKeys:
+		if len(forwarded) != 2 {
+			t.Errorf("got %d forwarded keys, expected 2", len(forwarded))
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("agent was not forwarded")
+	}
+
+	if _, err := s.Exec(MethodGetConfig("running")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	s.Close()
+	if _, err := a.client.List(); err == nil {
+		t.Errorf("agent connection not closed with the session")
+	}
+}
@@ -0,0 +1,111 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHAuthOptions holds the credentials offered when authenticating with
+// SSHConfigMultiAuth.
+type SSHAuthOptions struct {
+	// Signers are offered for public key authentication
+	Signers []ssh.Signer
+	// Password is used for password authentication and to answer the
+	// password prompts of keyboard-interactive authentication
+	Password string
+	// Responder answers the keyboard-interactive prompts not answered with
+	// Password, such as one time passwords
+	Responder ssh.KeyboardInteractiveChallenge
+}
+
+// SSHAuthMethods returns the authentication methods for opts in the order
+// they are tried: public key, password and keyboard-interactive.  Servers
+// requiring several methods, such as a password followed by a one time
+// password, are satisfied by trying the next methods after a partial success.
+func SSHAuthMethods(opts SSHAuthOptions) []ssh.AuthMethod {
+	var methods []ssh.AuthMethod
+	if len(opts.Signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(opts.Signers...))
+	}
+	if opts.Password != "" {
+		methods = append(methods, ssh.Password(opts.Password))
+	}
+	if opts.Password != "" || opts.Responder != nil {
+		methods = append(methods, ssh.KeyboardInteractive(KeyboardInteractivePassword(opts.Password, opts.Responder)))
+	}
+	return methods
+}
+
+// SSHConfigMultiAuth is a convenience function that takes a username and the
+// credentials described by opts and returns a new ssh.ClientConfig trying
+// them in the order given by SSHAuthMethods.  The HostKeyCallback is left
+// unset and must be provided, e.g. with SSHConfigKnownHosts.
+func SSHConfigMultiAuth(user string, opts SSHAuthOptions) *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User: user,
+		Auth: SSHAuthMethods(opts),
+	}
+}
+
+// SSHConfigKeyboardInteractive is a convenience function that takes a
+// username and a responder answering keyboard-interactive challenges and
+// returns a new ssh.ClientConfig using them.  The HostKeyCallback is left
+// unset and must be provided, e.g. with SSHConfigKnownHosts.
+func SSHConfigKeyboardInteractive(user string, responder ssh.KeyboardInteractiveChallenge) *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{
+			ssh.KeyboardInteractive(responder),
+		},
+	}
+}
+
+// KeyboardInteractivePassword returns a keyboard-interactive responder that
+// answers password prompts with password and passes the remaining prompts to
+// next.  A nil next fails challenges with prompts other than passwords.
+func KeyboardInteractivePassword(password string, next ssh.KeyboardInteractiveChallenge) ssh.KeyboardInteractiveChallenge {
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+
+		var pending []int
+		var pendingQuestions []string
+		var pendingEchos []bool
+		for i, q := range questions {
+			if password != "" && !echos[i] && strings.Contains(strings.ToLower(q), "password") {
+				answers[i] = password
+				continue
+			}
+			pending = append(pending, i)
+			pendingQuestions = append(pendingQuestions, q)
+			pendingEchos = append(pendingEchos, echos[i])
+		}
+
+		// Challenges without prompts only display instructions
+		if len(pending) == 0 {
+			return answers, nil
+		}
+		if next == nil {
+			return nil, fmt.Errorf("netconf ssh: no answer to keyboard-interactive prompt %q", pendingQuestions[0])
+		}
+
+		nextAnswers, err := next(name, instruction, pendingQuestions, pendingEchos)
+		if err != nil {
+			return nil, err
+		}
+		if len(nextAnswers) != len(pending) {
+			return nil, fmt.Errorf("netconf ssh: got %d keyboard-interactive answers, expected %d", len(nextAnswers), len(pending))
+		}
+		for i, idx := range pending {
+			answers[idx] = nextAnswers[i]
+		}
+		return answers, nil
+	}
+}
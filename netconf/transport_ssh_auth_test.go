@@ -0,0 +1,162 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestKeyboardInteractivePassword(t *testing.T) {
+	otp := func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i := range questions {
+			answers[i] = "123456"
+		}
+		return answers, nil
+	}
+
+	tt := []struct {
+		name      string
+		next      ssh.KeyboardInteractiveChallenge
+		questions []string
+		echos     []bool
+		expected  []string
+		err       bool
+	}{
+		{
+			name:      "password",
+			questions: []string{"Password: "},
+			echos:     []bool{false},
+			expected:  []string{"secret"},
+		},
+		{
+			name:      "passwordAndOTP",
+			next:      otp,
+			questions: []string{"Password: ", "Verification code: "},
+			echos:     []bool{false, true},
+			expected:  []string{"secret", "123456"},
+		},
+		{
+			name:      "noResponder",
+			questions: []string{"Verification code: "},
+			echos:     []bool{true},
+			err:       true,
+		},
+		{
+			name:     "instructions",
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			responder := KeyboardInteractivePassword("secret", tc.next)
+			answers, err := responder("", "", tc.questions, tc.echos)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !cmp.Equal(answers, tc.expected) {
+				t.Errorf("unexpected answers:\n%s", cmp.Diff(tc.expected, answers))
+			}
+		})
+	}
+}
+
+func TestSSHAuthMethods(t *testing.T) {
+	methods := SSHAuthMethods(SSHAuthOptions{
+		Signers:  []ssh.Signer{newTestSigner(t)},
+		Password: "secret",
+	})
+	if len(methods) != 3 {
+		t.Errorf("got %d methods, expected 3", len(methods))
+	}
+
+	if methods := SSHAuthMethods(SSHAuthOptions{}); len(methods) != 0 {
+		t.Errorf("got %d methods, expected none", len(methods))
+	}
+}
+
+func TestSSHConfigMultiAuth(t *testing.T) {
+	clientKey := newTestSigner(t)
+
+	tt := []struct {
+		name   string
+		server *ssh.ServerConfig
+		opts   SSHAuthOptions
+	}{
+		{
+			name: "publicKey",
+			server: &ssh.ServerConfig{
+				PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+					if bytes.Equal(key.Marshal(), clientKey.PublicKey().Marshal()) {
+						return nil, nil
+					}
+					return nil, fmt.Errorf("unknown key")
+				},
+			},
+			opts: SSHAuthOptions{Signers: []ssh.Signer{clientKey}, Password: "wrong"},
+		},
+		{
+			name: "keyboardInteractiveOTP",
+			server: &ssh.ServerConfig{
+				PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+					return nil, fmt.Errorf("password authentication disabled")
+				},
+				KeyboardInteractiveCallback: func(conn ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+					answers, err := challenge("", "", []string{"Password: ", "Verification code: "}, []bool{false, true})
+					if err != nil {
+						return nil, err
+					}
+					if len(answers) != 2 || answers[0] != "secret" || answers[1] != "123456" {
+						return nil, fmt.Errorf("wrong answers")
+					}
+					return nil, nil
+				},
+			},
+			opts: SSHAuthOptions{
+				Password: "secret",
+				Responder: func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+					return []string{"123456"}, nil
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestSSHServer(t, tc.server)
+			defer server.Close()
+
+			config := SSHConfigMultiAuth("test", tc.opts)
+			config.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+
+			s, err := DialSSH(server.Addr(), config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer s.Close()
+
+			if s.SessionID != 42 {
+				t.Errorf("got session-id %d, expected 42", s.SessionID)
+			}
+			if _, err := s.Exec(MethodGetConfig("running")); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,581 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHConfigFile holds the Host sections of an OpenSSH ssh_config file.
+// Match sections are not supported and ignored.
+type SSHConfigFile struct {
+	hosts []sshConfigHost
+}
+
+// sshConfigHost is a Host section with its lowercased keywords and arguments
+type sshConfigHost struct {
+	patterns []string
+	// within holds the patterns of the Host sections of the files including
+	// the one of the section, which must match too
+	within  [][]string
+	options []sshConfigOption
+}
+
+// sshConfigMaxDepth is the maximum nesting of Include directives, as in
+// OpenSSH
+const sshConfigMaxDepth = 16
+
+type sshConfigOption struct {
+	keyword string
+	args    []string
+}
+
+// SSHHostConfig holds the settings of a host alias resolved from a ssh_config
+// file.  Percent tokens and ~ are expanded.
+type SSHHostConfig struct {
+	Alias    string
+	HostName string
+	// Port is 0 when not set, meaning the NETCONF port for targets and the
+	// SSH port for jump hosts
+	Port          int
+	User          string
+	IdentityFiles []string
+	// ProxyJump holds the [user@]host[:port] jump hosts in order
+	ProxyJump           []string
+	ProxyCommand        string
+	UserKnownHostsFiles []string
+	// StrictHostKeyChecking is yes, accept-new, no or ask (the default,
+	// handled as yes as there is no one to ask)
+	StrictHostKeyChecking string
+}
+
+// LoadSSHConfigFile parses the ssh_config file at path, ~/.ssh/config when
+// path is empty.  A missing file is handled as an empty one.
+func LoadSSHConfigFile(path string) (*SSHConfigFile, error) {
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, ".ssh", "config")
+	}
+	return loadSSHConfigFile(path, 0)
+}
+
+// loadSSHConfigFile parses the ssh_config file at path, included at the given
+// depth.
+func loadSSHConfigFile(path string, depth int) (*SSHConfigFile, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &SSHConfigFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseSSHConfig(f, depth)
+}
+
+// ParseSSHConfig parses an OpenSSH ssh_config file.  Include directives are
+// resolved relative to ~/.ssh, the included files taking the place of the
+// directive.
+func ParseSSHConfig(r io.Reader) (*SSHConfigFile, error) {
+	return parseSSHConfig(r, 0)
+}
+
+func parseSSHConfig(r io.Reader, depth int) (*SSHConfigFile, error) {
+	c := &SSHConfigFile{}
+
+	// Options before the first Host section apply to all hosts
+	current := &sshConfigHost{patterns: []string{"*"}}
+	skip := false
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		keyword, args, err := parseSSHConfigLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("ssh_config line %d: %v", lineNum, err)
+		}
+
+		switch keyword {
+		case "":
+			continue
+		case "host":
+			c.hosts = append(c.hosts, *current)
+			current = &sshConfigHost{patterns: args}
+			skip = false
+		case "match":
+			c.hosts = append(c.hosts, *current)
+			current = &sshConfigHost{}
+			skip = true
+		case "include":
+			// The options above the directive come first, and the included
+			// sections only apply within the current one
+			c.hosts = append(c.hosts, *current)
+			if !skip {
+				if err := c.include(args, current, depth+1); err != nil {
+					return nil, fmt.Errorf("ssh_config line %d: %v", lineNum, err)
+				}
+			}
+			current = &sshConfigHost{patterns: current.patterns, within: current.within}
+		default:
+			if !skip {
+				current.options = append(current.options, sshConfigOption{keyword, args})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	c.hosts = append(c.hosts, *current)
+	return c, nil
+}
+
+// include appends the Host sections of the files matching patterns, included
+// at the given depth from the enclosing section.
+func (c *SSHConfigFile) include(patterns []string, enclosing *sshConfigHost, depth int) error {
+	if depth > sshConfigMaxDepth {
+		return fmt.Errorf("include nested too deeply")
+	}
+
+	within := append(enclosing.within[:len(enclosing.within):len(enclosing.within)], enclosing.patterns)
+	for _, pattern := range patterns {
+		pattern = expandHome(pattern)
+		if !filepath.IsAbs(pattern) {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return err
+			}
+			pattern = filepath.Join(home, ".ssh", pattern)
+		}
+
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			included, err := loadSSHConfigFile(file, depth)
+			if err != nil {
+				return err
+			}
+			for _, host := range included.hosts {
+				host.within = append(within[:len(within):len(within)], host.within...)
+				c.hosts = append(c.hosts, host)
+			}
+		}
+	}
+	return nil
+}
+
+// parseSSHConfigLine splits a line into its lowercased keyword and arguments,
+// which can be quoted and separated from the keyword by an equal sign.
+func parseSSHConfigLine(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), nil, nil
+	}
+	keyword := strings.ToLower(line[:end])
+	rest := strings.TrimLeft(line[end:], " \t")
+	rest = strings.TrimLeft(strings.TrimPrefix(rest, "="), " \t")
+
+	var args []string
+	for rest != "" {
+		var arg string
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return "", nil, fmt.Errorf("unterminated quote")
+			}
+			arg, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			arg, rest = rest[:end], rest[end:]
+		}
+		args = append(args, arg)
+		rest = strings.TrimLeft(rest, " \t")
+	}
+	return keyword, args, nil
+}
+
+// Resolve returns the settings of the host alias.  As with OpenSSH the first
+// value found for a keyword wins, except for IdentityFile which accumulates.
+func (c *SSHConfigFile) Resolve(alias string) *SSHHostConfig {
+	h := &SSHHostConfig{Alias: alias}
+	seen := map[string]bool{}
+
+	for _, host := range c.hosts {
+		if !host.match(alias) {
+			continue
+		}
+
+		for _, opt := range host.options {
+			if len(opt.args) == 0 {
+				continue
+			}
+			if opt.keyword == "identityfile" {
+				h.IdentityFiles = append(h.IdentityFiles, opt.args[0])
+				continue
+			}
+			if seen[opt.keyword] {
+				continue
+			}
+			seen[opt.keyword] = true
+
+			switch opt.keyword {
+			case "hostname":
+				h.HostName = opt.args[0]
+			case "port":
+				h.Port, _ = strconv.Atoi(opt.args[0])
+			case "user":
+				h.User = opt.args[0]
+			case "proxyjump":
+				if opt.args[0] != "none" {
+					h.ProxyJump = strings.Split(opt.args[0], ",")
+				}
+			case "proxycommand":
+				if opt.args[0] != "none" {
+					h.ProxyCommand = strings.Join(opt.args, " ")
+				}
+			case "userknownhostsfile":
+				if opt.args[0] != "none" {
+					h.UserKnownHostsFiles = append([]string(nil), opt.args...)
+				}
+			case "stricthostkeychecking":
+				h.StrictHostKeyChecking = strings.ToLower(opt.args[0])
+			}
+		}
+	}
+
+	if h.HostName == "" {
+		h.HostName = "%h"
+	}
+	h.HostName = strings.Replace(h.HostName, "%h", alias, -1)
+	if h.User == "" {
+		if u, err := user.Current(); err == nil {
+			h.User = u.Username
+		}
+	}
+
+	for i, file := range h.IdentityFiles {
+		h.IdentityFiles[i] = h.expand(file)
+	}
+	for i, file := range h.UserKnownHostsFiles {
+		h.UserKnownHostsFiles[i] = h.expand(file)
+	}
+	h.ProxyCommand = h.expand(h.ProxyCommand)
+
+	return h
+}
+
+// match reports whether the section applies to the host alias
+func (h *sshConfigHost) match(alias string) bool {
+	for _, patterns := range h.within {
+		if !matchSSHHost(patterns, alias) {
+			return false
+		}
+	}
+	return matchSSHHost(h.patterns, alias)
+}
+
+// matchSSHHost reports whether host matches the patterns of a Host section,
+// none of the negated patterns matching it.
+func matchSSHHost(patterns []string, host string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") {
+			if matchSSHPattern(pattern[1:], host) {
+				return false
+			}
+			continue
+		}
+		if matchSSHPattern(pattern, host) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchSSHPattern matches host against a pattern using the * and ?
+// wildcards
+func matchSSHPattern(pattern string, host string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(host); i >= 0; i-- {
+				if matchSSHPattern(pattern[1:], host[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if host == "" {
+				return false
+			}
+		default:
+			if host == "" || !strings.EqualFold(pattern[:1], host[:1]) {
+				return false
+			}
+		}
+		pattern, host = pattern[1:], host[1:]
+	}
+	return host == ""
+}
+
+// expand replaces the %h, %p, %r, %n, %d, %u and %% tokens and a leading ~
+// in s
+func (h *SSHHostConfig) expand(s string) string {
+	if s == "" {
+		return s
+	}
+
+	home, _ := os.UserHomeDir()
+	localUser := ""
+	if u, err := user.Current(); err == nil {
+		localUser = u.Username
+	}
+
+	r := strings.NewReplacer(
+		"%%", "%",
+		"%h", h.HostName,
+		"%p", strconv.Itoa(h.port(sshDefaultPort)),
+		"%r", h.User,
+		"%n", h.Alias,
+		"%d", home,
+		"%u", localUser,
+	)
+	return expandHome(r.Replace(s))
+}
+
+func (h *SSHHostConfig) port(defaultPort int) int {
+	if h.Port == 0 {
+		return defaultPort
+	}
+	return h.Port
+}
+
+// Target returns the host:port address of the NETCONF server, using the
+// NETCONF over SSH port when the Port is not set.
+func (h *SSHHostConfig) Target() string {
+	return net.JoinHostPort(h.HostName, strconv.Itoa(h.port(sshDefaultPort)))
+}
+
+// ClientConfig returns a ssh.ClientConfig for the host.  The keys of the
+// IdentityFiles, or of the default ~/.ssh/id_* files, are offered first,
+// skipping the missing and encrypted ones, followed by auth.  Host keys are
+// verified according to StrictHostKeyChecking against the
+// UserKnownHostsFiles, ~/.ssh/known_hosts by default.
+func (h *SSHHostConfig) ClientConfig(auth ...ssh.AuthMethod) (*ssh.ClientConfig, error) {
+	config := &ssh.ClientConfig{User: h.User}
+
+	identityFiles := h.IdentityFiles
+	if len(identityFiles) == 0 {
+		identityFiles = []string{"~/.ssh/id_rsa", "~/.ssh/id_ecdsa", "~/.ssh/id_ed25519"}
+	}
+	var signers []ssh.Signer
+	for _, file := range identityFiles {
+		keys, err := SSHSignersFromFile(expandHome(file), "")
+		if err != nil {
+			continue
+		}
+		signers = append(signers, keys...)
+	}
+	if len(signers) > 0 {
+		config.Auth = append(config.Auth, ssh.PublicKeys(signers...))
+	}
+	config.Auth = append(config.Auth, auth...)
+
+	knownHostsFiles := h.UserKnownHostsFiles
+	if len(knownHostsFiles) == 0 {
+		knownHostsFiles = []string{expandHome("~/.ssh/known_hosts")}
+	}
+
+	var err error
+	switch h.StrictHostKeyChecking {
+	case "no", "off":
+		config.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+	case "accept-new":
+		config.HostKeyCallback, err = TrustOnFirstUseCallback(knownHostsFiles[0])
+	default:
+		var existing []string
+		for _, file := range knownHostsFiles {
+			if _, err := os.Stat(file); err == nil {
+				existing = append(existing, file)
+			}
+		}
+		// Always pass a file so hosts are unknown rather than using the
+		// default known_hosts when none exist
+		config.HostKeyCallback, err = KnownHostsCallback(append(existing, os.DevNull)...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Dial creates a new NETCONF session to the host alias using its settings,
+// going through its ProxyCommand or ProxyJump hosts when set.  Jump hosts are
+// resolved in the same file.  auth is offered after the identity files, see
+// SSHHostConfig.ClientConfig.
+func (c *SSHConfigFile) Dial(alias string, auth ...ssh.AuthMethod) (*Session, error) {
+	h := c.Resolve(alias)
+	config, err := h.ClientConfig(auth...)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.ProxyCommand != "" {
+		conn, err := dialProxyCommand(h.ProxyCommand)
+		if err != nil {
+			sessionFailed(err)
+			return nil, err
+		}
+		t, err := connAddrToTransport(conn, h.Target(), config)
+		if err != nil {
+			conn.Close()
+			sessionFailed(err)
+			return nil, err
+		}
+		return NewSession(t), nil
+	}
+
+	var jumps []SSHJumpHost
+	for _, spec := range h.ProxyJump {
+		jump, err := c.resolveJump(spec, auth)
+		if err != nil {
+			return nil, err
+		}
+		jumps = append(jumps, jump)
+	}
+
+	return DialSSHJump(h.Target(), config, jumps...)
+}
+
+// resolveJump resolves a [user@]host[:port] ProxyJump entry
+func (c *SSHConfigFile) resolveJump(spec string, auth []ssh.AuthMethod) (SSHJumpHost, error) {
+	var jumpUser string
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		jumpUser, spec = spec[:i], spec[i+1:]
+	}
+
+	alias, port := spec, 0
+	if host, p, err := net.SplitHostPort(spec); err == nil {
+		alias = host
+		port, _ = strconv.Atoi(p)
+	}
+
+	h := c.Resolve(alias)
+	if jumpUser != "" {
+		h.User = jumpUser
+	}
+	if port != 0 {
+		h.Port = port
+	}
+
+	config, err := h.ClientConfig(auth...)
+	if err != nil {
+		return SSHJumpHost{}, err
+	}
+	return SSHJumpHost{
+		Target: net.JoinHostPort(h.HostName, strconv.Itoa(h.port(22))),
+		Config: config,
+	}, nil
+}
+
+// DialSSHConfig creates a new NETCONF session to the host alias using the
+// settings of ~/.ssh/config.  See SSHConfigFile.Dial.
+func DialSSHConfig(alias string, auth ...ssh.AuthMethod) (*Session, error) {
+	c, err := LoadSSHConfigFile("")
+	if err != nil {
+		return nil, err
+	}
+	return c.Dial(alias, auth...)
+}
+
+// commandConn is a net.Conn over the standard input and output of a
+// ProxyCommand
+type commandConn struct {
+	io.Reader
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+// dialProxyCommand starts command with the shell and returns a connection
+// over its standard input and output
+func dialProxyCommand(command string) (net.Conn, error) {
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Stderr = os.Stderr
+
+	w, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	r, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &commandConn{Reader: r, WriteCloser: w, cmd: cmd}, nil
+}
+
+// Close closes the command input and stops it
+func (c *commandConn) Close() error {
+	c.WriteCloser.Close()
+	c.cmd.Process.Kill()
+	c.cmd.Wait()
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr                { return commandAddr{} }
+func (c *commandConn) RemoteAddr() net.Addr               { return commandAddr{} }
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type commandAddr struct{}
+
+func (commandAddr) Network() string { return "proxycommand" }
+func (commandAddr) String() string  { return "proxycommand" }
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,279 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const testSSHConfig = `
+# Global options
+StrictHostKeyChecking accept-new
+
+Host r1 r2
+    HostName %h.lab.example.net
+    Port 2830
+    User admin
+    IdentityFile ~/.ssh/lab_%r
+
+Host r1
+    Port 22
+    IdentityFile "/etc/keys/r1 key"
+
+Host *.example.net !bastion.example.net
+    ProxyJump ops@bastion.example.net:2222,bastion2
+    UserKnownHostsFile /etc/ssh/known_hosts_lab /etc/ssh/known_hosts_lab2
+
+Match host foo
+    User ignored
+
+Host proxied
+    ProxyCommand=ssh -W %h:%p jump
+    StrictHostKeyChecking no
+`
+
+func TestSSHConfigResolve(t *testing.T) {
+	c, err := ParseSSHConfig(strings.NewReader(testSSHConfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	home, _ := os.UserHomeDir()
+	tt := []struct {
+		alias    string
+		expected *SSHHostConfig
+	}{
+		{
+			alias: "r1",
+			expected: &SSHHostConfig{
+				Alias:                 "r1",
+				HostName:              "r1.lab.example.net",
+				Port:                  2830,
+				User:                  "admin",
+				IdentityFiles:         []string{filepath.Join(home, ".ssh/lab_admin"), "/etc/keys/r1 key"},
+				StrictHostKeyChecking: "accept-new",
+			},
+		},
+		{
+			alias: "db.example.net",
+			expected: &SSHHostConfig{
+				Alias:                 "db.example.net",
+				HostName:              "db.example.net",
+				ProxyJump:             []string{"ops@bastion.example.net:2222", "bastion2"},
+				UserKnownHostsFiles:   []string{"/etc/ssh/known_hosts_lab", "/etc/ssh/known_hosts_lab2"},
+				StrictHostKeyChecking: "accept-new",
+			},
+		},
+		{
+			alias: "bastion.example.net",
+			expected: &SSHHostConfig{
+				Alias:                 "bastion.example.net",
+				HostName:              "bastion.example.net",
+				StrictHostKeyChecking: "accept-new",
+			},
+		},
+		{
+			alias: "proxied",
+			expected: &SSHHostConfig{
+				Alias:                 "proxied",
+				HostName:              "proxied",
+				ProxyCommand:          "ssh -W proxied:830 jump",
+				StrictHostKeyChecking: "accept-new",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.alias, func(t *testing.T) {
+			h := c.Resolve(tc.alias)
+			if tc.expected.User == "" {
+				tc.expected.User = h.User
+			}
+			if !cmp.Equal(h, tc.expected) {
+				t.Errorf("unexpected host config:\n%s", cmp.Diff(tc.expected, h))
+			}
+		})
+	}
+
+	if target := c.Resolve("r2").Target(); target != "r2.lab.example.net:2830" {
+		t.Errorf("got target %s, expected r2.lab.example.net:2830", target)
+	}
+	if target := c.Resolve("other").Target(); target != "other:830" {
+		t.Errorf("got target %s, expected other:830", target)
+	}
+}
+
+func TestSSHConfigInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"extra":   "Host *\n    User included\n    Port 2830\n",
+		"lab":     "HostName %h.lab.example.net\nHost r2\n    Port 2222\n",
+		"recurse": "Include " + filepath.Join(dir, "recurse") + "\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := ParseSSHConfig(strings.NewReader(fmt.Sprintf(`
+User global
+Include %s
+Host r1
+    Include %s
+Host r2
+    Port 22
+`, filepath.Join(dir, "extra"), filepath.Join(dir, "lab"))))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Options above the directive win over the included ones, which win
+	// over the ones below it
+	h := c.Resolve("r1")
+	if h.User != "global" || h.Port != 2830 || h.HostName != "r1.lab.example.net" {
+		t.Errorf("unexpected host config %+v", h)
+	}
+	// The sections included within Host r1 only apply to r1
+	h = c.Resolve("r2")
+	if h.User != "global" || h.Port != 2830 || h.HostName != "r2" {
+		t.Errorf("unexpected host config %+v", h)
+	}
+
+	_, err = ParseSSHConfig(strings.NewReader(files["recurse"]))
+	if err == nil || !strings.HasSuffix(err.Error(), "include nested too deeply") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseSSHConfigError(t *testing.T) {
+	_, err := ParseSSHConfig(strings.NewReader("Host r1\n  ProxyCommand \"unterminated\n"))
+	if err == nil {
+		t.Errorf("expected error for unterminated quote")
+	}
+}
+
+func TestMatchSSHPattern(t *testing.T) {
+	tt := []struct {
+		pattern string
+		host    string
+		match   bool
+	}{
+		{"*", "r1", true},
+		{"r?", "r1", true},
+		{"r?", "r10", false},
+		{"*.example.net", "R1.Example.NET", true},
+		{"*.example.net", "example.net", false},
+		{"r*1", "r1", true},
+		{"r*1", "r2", false},
+	}
+
+	for _, tc := range tt {
+		if matchSSHPattern(tc.pattern, tc.host) != tc.match {
+			t.Errorf("matchSSHPattern(%q, %q) != %v", tc.pattern, tc.host, tc.match)
+		}
+	}
+}
+
+func TestSSHConfigDial(t *testing.T) {
+	bastion := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer bastion.Close()
+	device := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer device.Close()
+
+	dir, err := ioutil.TempDir("", "netconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bastionHost, bastionPort, _ := net.SplitHostPort(bastion.Addr())
+	deviceHost, devicePort, _ := net.SplitHostPort(device.Addr())
+	knownHosts := filepath.Join(dir, "known_hosts")
+
+	c, err := ParseSSHConfig(strings.NewReader(fmt.Sprintf(`
+Host bastion
+    HostName %s
+    Port %s
+
+Host device
+    HostName %s
+    Port %s
+    ProxyJump bastion
+
+Host *
+    UserKnownHostsFile %s
+    StrictHostKeyChecking accept-new
+    IdentityFile /nonexistent
+`, bastionHost, bastionPort, deviceHost, devicePort, knownHosts)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s, err := c.Dial("device")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Exec(MethodGetConfig("running")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Both host keys were learned on first use
+	f, err := os.Open(knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var hosts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		_, h, _, _, _, err := ssh.ParseKnownHosts(scanner.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		hosts = append(hosts, h[0])
+	}
+	expected := []string{knownhosts.Normalize(bastion.Addr()), knownhosts.Normalize(device.Addr())}
+	if !cmp.Equal(hosts, expected) {
+		t.Errorf("unexpected known hosts:\n%s", cmp.Diff(expected, hosts))
+	}
+}
+
+func TestDialProxyCommand(t *testing.T) {
+	conn, err := dialProxyCommand("cat")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "hello\n" {
+		t.Errorf("got %q (%v), expected echo of hello", line, err)
+	}
+}
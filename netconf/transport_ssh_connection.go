@@ -0,0 +1,168 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrSSHConnectionClosed is returned when opening a session on a closed
+// SSHConnection.
+var ErrSSHConnectionClosed = errors.New("netconf ssh: connection closed")
+
+// SSHConnection is a single authenticated SSH connection on which many
+// independent NETCONF sessions are opened, each one using its own channel.
+// The connection is reference counted: closing the last session opened on it
+// closes the underlying ssh.Client.
+type SSHConnection struct {
+	mu        sync.Mutex
+	client    *ssh.Client
+	sessions  int
+	closed    bool
+	keepalive *sshKeepalive
+	// err is set when the keepalive found the connection dead
+	err error
+}
+
+// DialSSHConnection connects and authenticates to target without opening a
+// NETCONF session.  See TransportSSH.Dial for arguments.
+func DialSSHConnection(target string, config *ssh.ClientConfig) (*SSHConnection, error) {
+	if !strings.Contains(target, ":") {
+		target = fmt.Sprintf("%s:%d", target, sshDefaultPort)
+	}
+
+	client, err := ssh.Dial("tcp", target, config)
+	if err != nil {
+		sessionFailed(err)
+		return nil, err
+	}
+	return NewSSHConnection(client), nil
+}
+
+// NewSSHConnection creates a SSHConnection from an existing ssh.Client, which
+// is then owned and closed by the SSHConnection.  DefaultSSHKeepalive is
+// started on the connection.
+func NewSSHConnection(client *ssh.Client) *SSHConnection {
+	c := &SSHConnection{client: client}
+	c.StartKeepalive(DefaultSSHKeepalive)
+	return c
+}
+
+// StartKeepalive sends keepalive requests on the connection as configured by
+// k, replacing the running keepalive if any.  A single keepalive probes the
+// connection for all its sessions.  When the connection is found dead it is
+// closed, failing the operations of its sessions with a *KeepaliveError.
+func (c *SSHConnection) StartKeepalive(k SSHKeepalive) {
+	c.StopKeepalive()
+
+	ka := startSSHKeepalive(c.client, k, c.markDead)
+	c.mu.Lock()
+	c.keepalive = ka
+	c.mu.Unlock()
+}
+
+// StopKeepalive stops the running keepalive and waits for it to exit.
+func (c *SSHConnection) StopKeepalive() {
+	c.mu.Lock()
+	ka := c.keepalive
+	c.keepalive = nil
+	c.mu.Unlock()
+
+	ka.Stop()
+}
+
+// Err returns the *KeepaliveError which marked the connection dead, or nil.
+func (c *SSHConnection) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// markDead records err and closes the connection, unblocking the pending
+// reads of its sessions
+func (c *SSHConnection) markDead(err error) {
+	c.mu.Lock()
+	c.err = err
+	c.closed = true
+	c.mu.Unlock()
+
+	c.client.Close()
+}
+
+// NewSession opens a new NETCONF session on a new channel of the connection.
+func (c *SSHConnection) NewSession() (*Session, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrSSHConnectionClosed
+	}
+	c.sessions++
+	c.mu.Unlock()
+
+	t := &TransportSSH{
+		sshClient:      c.client,
+		managedSession: true,
+		conn:           c,
+	}
+	if err := t.setupSession(); err != nil {
+		t.Close()
+		sessionFailed(err)
+		return nil, err
+	}
+
+	return NewSession(t), nil
+}
+
+// Sessions returns the number of sessions open on the connection.
+func (c *SSHConnection) Sessions() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessions
+}
+
+// Client returns the underlying ssh.Client.
+func (c *SSHConnection) Client() *ssh.Client {
+	return c.client
+}
+
+// Close closes the connection immediately, terminating the sessions still
+// open on it.
+func (c *SSHConnection) Close() error {
+	c.StopKeepalive()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.client.Close()
+}
+
+// release drops the reference held by a session, closing the connection once
+// no session is left
+func (c *SSHConnection) release() error {
+	c.mu.Lock()
+	c.sessions--
+	if c.sessions > 0 || c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	ka := c.keepalive
+	c.keepalive = nil
+	c.mu.Unlock()
+
+	ka.Stop()
+	return c.client.Close()
+}
@@ -0,0 +1,98 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHConnection(t *testing.T) {
+	var logins int32
+	server := newTestSSHServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			atomic.AddInt32(&logins, 1)
+			return nil, nil
+		},
+	})
+	defer server.Close()
+
+	c, err := DialSSHConnection(server.Addr(), SSHConfigPassword("test", "secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var sessions []*Session
+	for i := 0; i < 3; i++ {
+		s, err := c.NewSession()
+		if err != nil {
+			t.Fatalf("session %d: unexpected error: %v", i, err)
+		}
+		sessions = append(sessions, s)
+	}
+	if n := c.Sessions(); n != 3 {
+		t.Errorf("got %d sessions, expected 3", n)
+	}
+
+	for i, s := range sessions {
+		if _, err := s.Exec(MethodGetConfig("running")); err != nil {
+			t.Errorf("session %d: unexpected error: %v", i, err)
+		}
+	}
+	if n := atomic.LoadInt32(&logins); n != 1 {
+		t.Errorf("got %d logins, expected 1", n)
+	}
+
+	// Closing a session leaves the others usable
+	if err := sessions[0].Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	sessions[0].Close()
+	if n := c.Sessions(); n != 2 {
+		t.Errorf("got %d sessions, expected 2", n)
+	}
+	if _, err := sessions[1].Exec(MethodGetConfig("running")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Closing the last session closes the connection
+	sessions[1].Close()
+	sessions[2].Close()
+	if _, err := c.NewSession(); err != ErrSSHConnectionClosed {
+		t.Errorf("got error %v, expected %v", err, ErrSSHConnectionClosed)
+	}
+	if err := c.Client().Wait(); err == nil {
+		t.Errorf("expected the ssh client to be closed")
+	}
+}
+
+func TestSSHConnectionClose(t *testing.T) {
+	server := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer server.Close()
+
+	c, err := DialSSHConnection(server.Addr(), SSHConfigPassword("test", ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, err := c.NewSession()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := s.Exec(MethodGetConfig("running")); err == nil {
+		t.Errorf("expected error on a session of a closed connection")
+	}
+	s.Close()
+	if _, err := c.NewSession(); err != ErrSSHConnectionClosed {
+		t.Errorf("got error %v, expected %v", err, ErrSSHConnectionClosed)
+	}
+}
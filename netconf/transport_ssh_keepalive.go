@@ -0,0 +1,203 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sshKeepaliveRequest is the global request used to probe the connection,
+// answered by OpenSSH and most other servers
+const sshKeepaliveRequest = "keepalive@openssh.com"
+
+// DefaultSSHKeepalive sets the keepalive started once per SSH connection
+// dialed by the package: by the Dial functions, NewSSHSession and
+// SSHConnection.  Keepalives are disabled when Interval is zero, the default.
+var DefaultSSHKeepalive SSHKeepalive
+
+// SSHKeepalive configures the keepalive requests detecting dead SSH
+// connections.
+type SSHKeepalive struct {
+	// Interval between two requests
+	Interval time.Duration
+	// MaxMissed is the number of consecutive intervals without reply after
+	// which the connection is dead, 3 by default
+	MaxMissed int
+}
+
+// KeepaliveError is returned by the operations of a SSH transport whose
+// connection was found dead by the keepalive.
+type KeepaliveError struct {
+	// Missed is the number of intervals without reply
+	Missed   int
+	Interval time.Duration
+	// Err is the error sending the request, if any
+	Err error
+}
+
+// Error generates a string representation of the keepalive failure
+func (e *KeepaliveError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("netconf ssh: keepalive failed: %v", e.Err)
+	}
+	return fmt.Sprintf("netconf ssh: connection dead, %d keepalives unanswered in %s", e.Missed, time.Duration(e.Missed)*e.Interval)
+}
+
+// sshKeepalive tracks a running keepalive goroutine
+type sshKeepalive struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startSSHKeepalive sends keepalive requests on client as configured by k,
+// calling dead once the connection is found dead.  It returns nil when k
+// disables keepalives.
+func startSSHKeepalive(client *ssh.Client, k SSHKeepalive, dead func(err error)) *sshKeepalive {
+	if k.Interval <= 0 {
+		return nil
+	}
+	if k.MaxMissed <= 0 {
+		k.MaxMissed = 3
+	}
+
+	ka := &sshKeepalive{stop: make(chan struct{}), done: make(chan struct{})}
+	go ka.run(client, k, dead)
+	return ka
+}
+
+// Stop stops the keepalive and waits for it to exit.  It must not be called
+// by the dead function.
+func (ka *sshKeepalive) Stop() {
+	if ka != nil {
+		close(ka.stop)
+		<-ka.done
+	}
+}
+
+func (ka *sshKeepalive) run(client *ssh.Client, k SSHKeepalive, dead func(err error)) {
+	defer close(ka.done)
+
+	ticker := time.NewTicker(k.Interval)
+	defer ticker.Stop()
+
+	// A single request is outstanding at a time, as a dead connection
+	// never answers
+	var reply chan error
+	missed := 0
+	for {
+		select {
+		case <-ka.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if reply != nil {
+			select {
+			case err := <-reply:
+				if err != nil {
+					dead(&KeepaliveError{Missed: missed, Interval: k.Interval, Err: err})
+					return
+				}
+				reply, missed = nil, 0
+			default:
+				missed++
+				if missed >= k.MaxMissed {
+					dead(&KeepaliveError{Missed: missed, Interval: k.Interval})
+					return
+				}
+				continue
+			}
+		}
+
+		// Any reply, even a failure, shows the server is alive
+		reply = make(chan error, 1)
+		go func(reply chan<- error) {
+			_, _, err := client.SendRequest(sshKeepaliveRequest, true, nil)
+			reply <- err
+		}(reply)
+	}
+}
+
+// StartKeepalive sends keepalive requests on the SSH connection as configured
+// by k, replacing the running keepalive if any.  When the connection is found
+// dead, pending and later operations of the transport fail with a
+// *KeepaliveError.  The connection is closed when the transport owns it,
+// otherwise only the session channel is.  The keepalive stops when the
+// transport is closed.
+//
+// Every transport sends its own requests, so sessions sharing a SSHConnection
+// should rely on SSHConnection.StartKeepalive instead.
+func (t *TransportSSH) StartKeepalive(k SSHKeepalive) {
+	t.StopKeepalive()
+
+	ka := startSSHKeepalive(t.sshClient, k, t.markDead)
+	t.mu.Lock()
+	t.keepalive = ka
+	t.mu.Unlock()
+}
+
+// StopKeepalive stops the running keepalive and waits for it to exit.
+func (t *TransportSSH) StopKeepalive() {
+	t.mu.Lock()
+	ka := t.keepalive
+	t.keepalive = nil
+	t.mu.Unlock()
+
+	ka.Stop()
+}
+
+// Err returns the *KeepaliveError which marked the transport or the
+// SSHConnection it was opened on dead, or nil.
+func (t *TransportSSH) Err() error {
+	t.mu.Lock()
+	err, conn := t.err, t.conn
+	t.mu.Unlock()
+
+	if err == nil && conn != nil {
+		return conn.Err()
+	}
+	return err
+}
+
+// Send sends a message, returning the keepalive error of a dead transport.
+func (t *TransportSSH) Send(data []byte) error {
+	if err := t.Err(); err != nil {
+		return err
+	}
+	err := t.TransportBasicIO.Send(data)
+	if kerr := t.Err(); err != nil && kerr != nil {
+		return kerr
+	}
+	return err
+}
+
+// Receive receives a message, returning the keepalive error of a dead
+// transport.
+func (t *TransportSSH) Receive() ([]byte, error) {
+	b, err := t.TransportBasicIO.Receive()
+	if kerr := t.Err(); err != nil && kerr != nil {
+		return nil, kerr
+	}
+	return b, err
+}
+
+// markDead records err and closes the connection, or the session channel of
+// a connection the transport does not own, unblocking pending reads
+func (t *TransportSSH) markDead(err error) {
+	t.mu.Lock()
+	t.err = err
+	t.mu.Unlock()
+
+	if !t.managedSession {
+		t.sshClient.Close()
+	} else if t.sshSession != nil {
+		t.sshSession.Close()
+	}
+}
@@ -0,0 +1,184 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHKeepalive(t *testing.T) {
+	server := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer server.Close()
+
+	s, err := DialSSH(server.Addr(), SSHConfigPassword("test", ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	transport := s.Transport.(*TransportSSH)
+	transport.StartKeepalive(SSHKeepalive{Interval: 10 * time.Millisecond, MaxMissed: 2})
+
+	// Answered keepalives keep the session alive
+	time.Sleep(100 * time.Millisecond)
+	if err := transport.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Exec(MethodGetConfig("running")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A pending receive fails once keepalives go unanswered
+	received := make(chan error, 1)
+	go func() {
+		_, err := transport.Receive()
+		received <- err
+	}()
+	atomic.StoreInt32(&server.silent, 1)
+
+	select {
+	case err := <-received:
+		if _, ok := err.(*KeepaliveError); !ok {
+			t.Errorf("got error %v, expected *KeepaliveError", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("pending receive not failed")
+	}
+
+	if _, ok := transport.Err().(*KeepaliveError); !ok {
+		t.Errorf("got error %v, expected *KeepaliveError", transport.Err())
+	}
+	if _, err := s.Exec(MethodGetConfig("running")); err == nil {
+		t.Errorf("expected error on a dead session")
+	} else if _, ok := err.(*KeepaliveError); !ok {
+		t.Errorf("got error %v, expected *KeepaliveError", err)
+	}
+}
+
+func TestSSHKeepaliveStop(t *testing.T) {
+	server := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer server.Close()
+
+	defer func(k SSHKeepalive) { DefaultSSHKeepalive = k }(DefaultSSHKeepalive)
+	DefaultSSHKeepalive = SSHKeepalive{Interval: 10 * time.Millisecond}
+
+	s, err := DialSSH(server.Addr(), SSHConfigPassword("test", ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	transport := s.Transport.(*TransportSSH)
+	transport.mu.Lock()
+	ka := transport.keepalive
+	transport.mu.Unlock()
+	if ka == nil {
+		t.Fatalf("default keepalive not started")
+	}
+
+	s.Close()
+	select {
+	case <-ka.done:
+	default:
+		t.Errorf("keepalive still running after close")
+	}
+	if err := transport.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSSHConnectionKeepalive(t *testing.T) {
+	server := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer server.Close()
+
+	defer func(k SSHKeepalive) { DefaultSSHKeepalive = k }(DefaultSSHKeepalive)
+	DefaultSSHKeepalive = SSHKeepalive{Interval: 10 * time.Millisecond, MaxMissed: 2}
+
+	c, err := DialSSHConnection(server.Addr(), SSHConfigPassword("test", ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+
+	// A single keepalive probes the connection for all its sessions
+	var sessions []*Session
+	for i := 0; i < 2; i++ {
+		s, err := c.NewSession()
+		if err != nil {
+			t.Fatalf("session %d: unexpected error: %v", i, err)
+		}
+		defer s.Close()
+		sessions = append(sessions, s)
+
+		transport := s.Transport.(*TransportSSH)
+		transport.mu.Lock()
+		ka := transport.keepalive
+		transport.mu.Unlock()
+		if ka != nil {
+			t.Errorf("session %d: keepalive started on a shared connection", i)
+		}
+	}
+
+	atomic.StoreInt32(&server.silent, 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Err() == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := c.Err().(*KeepaliveError); !ok {
+		t.Fatalf("got error %v, expected *KeepaliveError", c.Err())
+	}
+
+	for i, s := range sessions {
+		if _, err := s.Exec(MethodGetConfig("running")); err == nil {
+			t.Errorf("session %d: expected error on a dead connection", i)
+		} else if _, ok := err.(*KeepaliveError); !ok {
+			t.Errorf("session %d: got error %v, expected *KeepaliveError", i, err)
+		}
+	}
+}
+
+func TestSSHKeepaliveClientNotOwned(t *testing.T) {
+	server := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer server.Close()
+
+	client, err := ssh.Dial("tcp", server.Addr(), SSHConfigPassword("test", ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer client.Close()
+
+	s, err := NewSSHClientSession(client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	transport := s.Transport.(*TransportSSH)
+	transport.StartKeepalive(SSHKeepalive{Interval: 10 * time.Millisecond, MaxMissed: 2})
+	atomic.StoreInt32(&server.silent, 1)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for transport.Err() == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := transport.Err().(*KeepaliveError); !ok {
+		t.Fatalf("got error %v, expected *KeepaliveError", transport.Err())
+	}
+
+	// The client of the caller is left open
+	other, err := NewSSHClientSession(client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer other.Close()
+	if _, err := other.Exec(MethodGetConfig("running")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyMismatchError is returned when the host key presented by the remote
+// device differs from the ones recorded in known_hosts.
+type HostKeyMismatchError struct {
+	Hostname string
+	Remote   net.Addr
+	Key      ssh.PublicKey
+	// Want holds the known keys for the host
+	Want []knownhosts.KnownKey
+}
+
+// Error generates a string representation of the host key mismatch
+func (e *HostKeyMismatchError) Error() string {
+	want := make([]string, len(e.Want))
+	for i, k := range e.Want {
+		want[i] = fmt.Sprintf("%s (%s:%d)", ssh.FingerprintSHA256(k.Key), k.Filename, k.Line)
+	}
+	return fmt.Sprintf("netconf ssh: host key mismatch for %s: got %s, expected %s",
+		e.Hostname, ssh.FingerprintSHA256(e.Key), strings.Join(want, ", "))
+}
+
+// HostKeyUnknownError is returned when known_hosts has no key for the remote
+// device.
+type HostKeyUnknownError struct {
+	Hostname string
+	Remote   net.Addr
+	Key      ssh.PublicKey
+}
+
+// Error generates a string representation of the unknown host key
+func (e *HostKeyUnknownError) Error() string {
+	return fmt.Sprintf("netconf ssh: unknown host key %s %s for %s",
+		e.Key.Type(), ssh.FingerprintSHA256(e.Key), e.Hostname)
+}
+
+// KnownHostsCallback returns a ssh.HostKeyCallback verifying the remote host
+// key against OpenSSH known_hosts files, ~/.ssh/known_hosts by default.
+// Hashed hostnames, port qualified hosts and @cert-authority and @revoked
+// markers are supported.  Verification failures are returned as
+// *HostKeyMismatchError, *HostKeyUnknownError or *knownhosts.RevokedError.
+func KnownHostsCallback(files ...string) (ssh.HostKeyCallback, error) {
+	if len(files) == 0 {
+		file, err := defaultKnownHostsFile()
+		if err != nil {
+			return nil, err
+		}
+		files = []string{file}
+	}
+
+	callback, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return hostKeyError(hostname, remote, key, callback(hostname, remote, key))
+	}, nil
+}
+
+// TrustOnFirstUseCallback returns a ssh.HostKeyCallback verifying the remote
+// host key against the known_hosts file, which is created if needed.  Keys of
+// hosts missing from the file are trusted and appended to it, while changed
+// keys are rejected with *HostKeyMismatchError.
+func TrustOnFirstUseCallback(file string) (ssh.HostKeyCallback, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	var mu sync.Mutex
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Reload the file so keys learned by other sessions are known
+		callback, err := knownhosts.New(file)
+		if err != nil {
+			return err
+		}
+
+		err = hostKeyError(hostname, remote, key, callback(hostname, remote, key))
+		if _, ok := err.(*HostKeyUnknownError); !ok {
+			return err
+		}
+
+		hosts := []string{knownhosts.Normalize(hostname)}
+		if remote != nil && knownhosts.Normalize(remote.String()) != hosts[0] {
+			hosts = append(hosts, knownhosts.Normalize(remote.String()))
+		}
+
+		f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = fmt.Fprintln(f, knownhosts.Line(hosts, key))
+		return err
+	}, nil
+}
+
+// SSHConfigKnownHosts sets the HostKeyCallback of config to verify the
+// remote host key against OpenSSH known_hosts files, ~/.ssh/known_hosts by
+// default.  See KnownHostsCallback.
+func SSHConfigKnownHosts(config *ssh.ClientConfig, files ...string) (*ssh.ClientConfig, error) {
+	callback, err := KnownHostsCallback(files...)
+	if err != nil {
+		return nil, err
+	}
+	config.HostKeyCallback = callback
+	return config, nil
+}
+
+// SSHConfigTrustOnFirstUse sets the HostKeyCallback of config to trust and
+// record the host key of unknown hosts in the known_hosts file.  See
+// TrustOnFirstUseCallback.
+func SSHConfigTrustOnFirstUse(config *ssh.ClientConfig, file string) (*ssh.ClientConfig, error) {
+	callback, err := TrustOnFirstUseCallback(file)
+	if err != nil {
+		return nil, err
+	}
+	config.HostKeyCallback = callback
+	return config, nil
+}
+
+// hostKeyError converts a knownhosts.KeyError into a typed host key error
+func hostKeyError(hostname string, remote net.Addr, key ssh.PublicKey, err error) error {
+	keyErr, ok := err.(*knownhosts.KeyError)
+	if !ok {
+		return err
+	}
+
+	if len(keyErr.Want) == 0 {
+		return &HostKeyUnknownError{Hostname: hostname, Remote: remote, Key: key}
+	}
+	return &HostKeyMismatchError{Hostname: hostname, Remote: remote, Key: key, Want: keyErr.Want}
+}
+
+func defaultKnownHostsFile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ssh", "known_hosts"), nil
+}
@@ -0,0 +1,188 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// newTestSigner generates a new ed25519 ssh.Signer
+func newTestSigner(t *testing.T) ssh.Signer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	return signer
+}
+
+func writeTestFile(t *testing.T, dir string, name string, content string) string {
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", file, err)
+	}
+	return file
+}
+
+func TestKnownHostsCallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hostKey := newTestSigner(t).PublicKey()
+	otherKey := newTestSigner(t).PublicKey()
+
+	ca := newTestSigner(t)
+	cert := &ssh.Certificate{
+		Key:             newTestSigner(t).PublicKey(),
+		CertType:        ssh.HostCert,
+		ValidPrincipals: []string{"r1.ca.example.org"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatalf("failed to sign certificate: %v", err)
+	}
+
+	file := writeTestFile(t, dir, "known_hosts", strings.Join([]string{
+		knownhosts.Line([]string{"plain.example.net"}, hostKey),
+		knownhosts.Line([]string{knownhosts.HashHostname("hashed.example.net")}, hostKey),
+		knownhosts.Line([]string{"[port.example.net]:2222"}, hostKey),
+		"@cert-authority *.ca.example.org " + string(ssh.MarshalAuthorizedKey(ca.PublicKey())),
+	}, "\n")+"\n")
+
+	callback, err := KnownHostsCallback(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 830}
+	tt := []struct {
+		name     string
+		hostname string
+		key      ssh.PublicKey
+		err      interface{}
+	}{
+		{"plain", "plain.example.net:22", hostKey, nil},
+		{"hashed", "hashed.example.net:22", hostKey, nil},
+		{"port", "port.example.net:2222", hostKey, nil},
+		{"cert", "r1.ca.example.org:22", cert, nil},
+		{"wrongPort", "port.example.net:22", hostKey, &HostKeyUnknownError{}},
+		{"mismatch", "plain.example.net:22", otherKey, &HostKeyMismatchError{}},
+		{"unknown", "unknown.example.org:22", hostKey, &HostKeyUnknownError{}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := callback(tc.hostname, remote, tc.key)
+			switch tc.err.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			case *HostKeyUnknownError:
+				if _, ok := err.(*HostKeyUnknownError); !ok {
+					t.Errorf("expected *HostKeyUnknownError, got %v", err)
+				}
+			case *HostKeyMismatchError:
+				if _, ok := err.(*HostKeyMismatchError); !ok {
+					t.Errorf("expected *HostKeyMismatchError, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestTrustOnFirstUseCallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "ssh", "known_hosts")
+	callback, err := TrustOnFirstUseCallback(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hostKey := newTestSigner(t).PublicKey()
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 830}
+
+	// First use records the key, later uses verify it
+	for i := 0; i < 2; i++ {
+		if err := callback("r1.example.net:22", remote, hostKey); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	err = callback("r1.example.net:22", remote, newTestSigner(t).PublicKey())
+	if _, ok := err.(*HostKeyMismatchError); !ok {
+		t.Errorf("expected *HostKeyMismatchError, got %v", err)
+	}
+
+	known, err := KnownHostsCallback(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := known("r1.example.net:22", remote, hostKey); err != nil {
+		t.Errorf("recorded key not accepted: %v", err)
+	}
+}
+
+func TestSSHConfigKnownHosts(t *testing.T) {
+	_, err := SSHConfigKnownHosts(SSHConfigPassword("test", "testPass"), "/nonexistent/known_hosts")
+	if err == nil {
+		t.Errorf("expected error for missing known_hosts file")
+	}
+}
+
+func TestSSHConfigPasswordKnownHosts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hostKey := newTestSigner(t).PublicKey()
+	file := writeTestFile(t, dir, "known_hosts", knownhosts.Line([]string{"r1.example.net"}, hostKey)+"\n")
+
+	config, err := SSHConfigPasswordKnownHosts("test", "testPass", file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.User != "test" || len(config.Auth) != 1 {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 830}
+	if err := config.HostKeyCallback("r1.example.net:22", remote, hostKey); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = config.HostKeyCallback("r1.example.net:22", remote, newTestSigner(t).PublicKey())
+	if _, ok := err.(*HostKeyMismatchError); !ok {
+		t.Errorf("got error %v, expected *HostKeyMismatchError", err)
+	}
+
+	if _, err := SSHConfigPasswordKnownHosts("test", "testPass", "/nonexistent/known_hosts"); err == nil {
+		t.Errorf("expected error for missing known_hosts file")
+	}
+}
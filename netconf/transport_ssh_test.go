@@ -7,12 +7,190 @@
 package netconf
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
+// testSSHServer is a minimal SSH server offering the netconf subsystem,
+// replying <ok/> to every rpc, and forwarding direct-tcpip channels.  The
+// keys of forwarded agents are sent to agentKeys.  Global requests such as
+// keepalives are left unanswered while silent is set.
+type testSSHServer struct {
+	listener  net.Listener
+	config    *ssh.ServerConfig
+	agentKeys chan []*agent.Key
+	silent    int32
+}
+
+// newTestSSHServer starts a testSSHServer on the loopback interface using a
+// newly generated host key.
+func newTestSSHServer(t *testing.T, config *ssh.ServerConfig) *testSSHServer {
+	config.AddHostKey(newTestSigner(t))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	s := &testSSHServer{listener: l, config: config, agentKeys: make(chan []*agent.Key, 1)}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serveConn(c)
+		}
+	}()
+	return s
+}
+
+func (s *testSSHServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *testSSHServer) Close() {
+	s.listener.Close()
+}
+
+func (s *testSSHServer) serveConn(c net.Conn) {
+	conn, chans, reqs, err := ssh.NewServerConn(c, s.config)
+	if err != nil {
+		c.Close()
+		return
+	}
+	defer conn.Close()
+	go func() {
+		for req := range reqs {
+			if req.WantReply && atomic.LoadInt32(&s.silent) == 0 {
+				req.Reply(false, nil)
+			}
+		}
+	}()
+
+	for newChan := range chans {
+		switch newChan.ChannelType() {
+		case "session":
+			ch, reqs, err := newChan.Accept()
+			if err != nil {
+				continue
+			}
+			go func() {
+				for req := range reqs {
+					switch {
+					case req.Type == "subsystem" && string(req.Payload[4:]) == sshNetconfSubsystem:
+						req.Reply(true, nil)
+						go serveTestNetconf(ch)
+					case req.Type == "auth-agent-req@openssh.com":
+						req.Reply(true, nil)
+						go s.listAgentKeys(conn)
+					default:
+						req.Reply(false, nil)
+					}
+				}
+			}()
+		case "direct-tcpip":
+			var target struct {
+				Host       string
+				Port       uint32
+				OriginHost string
+				OriginPort uint32
+			}
+			if err := ssh.Unmarshal(newChan.ExtraData(), &target); err != nil {
+				newChan.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			dst, err := net.Dial("tcp", net.JoinHostPort(target.Host, fmt.Sprint(target.Port)))
+			if err != nil {
+				newChan.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			ch, reqs, err := newChan.Accept()
+			if err != nil {
+				dst.Close()
+				continue
+			}
+			go ssh.DiscardRequests(reqs)
+			go func() {
+				io.Copy(ch, dst)
+				ch.Close()
+			}()
+			go func() {
+				io.Copy(dst, ch)
+				dst.Close()
+			}()
+		default:
+			newChan.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+// listAgentKeys sends the keys of the agent forwarded by the client to
+// agentKeys
+func (s *testSSHServer) listAgentKeys(conn ssh.Conn) {
+	ch, reqs, err := conn.OpenChannel("auth-agent@openssh.com", nil)
+	if err != nil {
+		return
+	}
+	defer ch.Close()
+	go ssh.DiscardRequests(reqs)
+
+	keys, _ := agent.NewClient(ch).List()
+	s.agentKeys <- keys
+}
+
+func serveTestNetconf(ch io.ReadWriteCloser) {
+	defer ch.Close()
+
+	if _, err := ch.Write([]byte(testServerHello + msgSeperator)); err != nil {
+		return
+	}
+
+	// The client hello and first rpc may arrive in a single read, so the
+	// messages are split here rather than with TransportBasicIO
+	r := bufio.NewReader(ch)
+	var msg []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		msg = append(msg, b)
+		if !bytes.HasSuffix(msg, []byte(msgSeperator)) {
+			continue
+		}
+
+		if !bytes.Contains(msg, []byte("<hello")) {
+			reply := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>` + msgSeperator
+			if _, err := ch.Write([]byte(reply)); err != nil {
+				return
+			}
+		}
+		msg = msg[:0]
+	}
+}
+
 func TestSSHConfigPassword(t *testing.T) {
 	user := "test"
 	password := "testPass"
@@ -44,3 +222,202 @@ func TestSSHConfigPassword(t *testing.T) {
 		t.Errorf("host key method of %s does not contain expected InsecureIgnoreHostKey", hostKeyMethod)
 	}
 }
+
+func TestSSHSignersFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := ssh.MarshalPrivateKeyWithPassphrase(edPriv, "test", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(ecPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8 := &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+
+	tt := []struct {
+		name       string
+		content    []byte
+		passphrase string
+		keys       int
+		err        bool
+	}{
+		{"openssh", pem.EncodeToMemory(encrypted), "secret", 1, false},
+		{"wrongPassphrase", pem.EncodeToMemory(encrypted), "wrong", 0, true},
+		{"missingPassphrase", pem.EncodeToMemory(encrypted), "", 0, true},
+		{"pkcs8", pem.EncodeToMemory(pkcs8), "", 1, false},
+		{"multiple", append(pem.EncodeToMemory(pkcs8), pem.EncodeToMemory(encrypted)...), "secret", 2, false},
+		{"empty", []byte("no keys here"), "", 0, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			file := writeTestFile(t, dir, tc.name, string(tc.content))
+
+			signers, err := SSHSignersFromFile(file, tc.passphrase)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(signers) != tc.keys {
+				t.Errorf("got %d signers, expected %d", len(signers), tc.keys)
+			}
+		})
+	}
+
+	// A certificate next to the key is offered before the key itself
+	ca := newTestSigner(t)
+	sshPub, err := ssh.NewPublicKey(edPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &ssh.Certificate{
+		Key:             sshPub,
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"test"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, "openssh-cert.pub", string(ssh.MarshalAuthorizedKey(cert)))
+
+	signers, err := SSHSignersFromFile(dir+"/openssh", "secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(signers) != 2 {
+		t.Fatalf("got %d signers, expected 2", len(signers))
+	}
+	if _, ok := signers[0].PublicKey().(*ssh.Certificate); !ok {
+		t.Errorf("expected certificate signer first, got %s", signers[0].PublicKey().Type())
+	}
+}
+
+func TestSSHConfigPubKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "netconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := writeTestFile(t, dir, "id_ed25519", string(pem.EncodeToMemory(block)))
+
+	res, err := SSHConfigPubKeyFile("test", file, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.User != "test" || len(res.Auth) != 1 {
+		t.Errorf("unexpected config %+v", res)
+	}
+}
+
+func TestDialSSHContext(t *testing.T) {
+	server := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer server.Close()
+
+	config := &ssh.ClientConfig{User: "test", HostKeyCallback: ssh.InsecureIgnoreHostKey()}
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := DialSSHContext(ctx, server.Addr(), config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// ctx no longer bounds the established session
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if _, err := s.Exec(MethodGetConfig("running")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	s.Close()
+
+	if _, err := DialSSHContext(ctx, server.Addr(), config); err != context.Canceled {
+		t.Errorf("got error %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestDialSSHJump(t *testing.T) {
+	bastion1 := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer bastion1.Close()
+	bastion2 := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer bastion2.Close()
+	device := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer device.Close()
+
+	var hostname string
+	config := &ssh.ClientConfig{
+		User: "test",
+		HostKeyCallback: func(h string, remote net.Addr, key ssh.PublicKey) error {
+			hostname = h
+			return nil
+		},
+	}
+	jumpConfig := &ssh.ClientConfig{User: "jump", HostKeyCallback: ssh.InsecureIgnoreHostKey()}
+
+	s, err := DialSSHJump(device.Addr(), config,
+		SSHJumpHost{Target: bastion1.Addr(), Config: jumpConfig},
+		SSHJumpHost{Target: bastion2.Addr(), Config: jumpConfig},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if hostname != device.Addr() {
+		t.Errorf("host key verified for %s, expected %s", hostname, device.Addr())
+	}
+	if _, err := s.Exec(MethodGetConfig("running")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	trans := s.Transport.(*TransportSSH)
+	jumpClients := trans.jumpClients
+	if len(jumpClients) != 2 {
+		t.Fatalf("got %d jump clients, expected 2", len(jumpClients))
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for i, c := range jumpClients {
+		if _, _, err := c.SendRequest("test", true, nil); err == nil {
+			t.Errorf("jump client %d not closed", i)
+		}
+	}
+}
+
+func TestDialSSHJumpFailure(t *testing.T) {
+	bastion := newTestSSHServer(t, &ssh.ServerConfig{NoClientAuth: true})
+	defer bastion.Close()
+
+	jumpConfig := &ssh.ClientConfig{User: "jump", HostKeyCallback: ssh.InsecureIgnoreHostKey()}
+	_, err := DialSSHJump("127.0.0.1:1", jumpConfig, SSHJumpHost{Target: bastion.Addr(), Config: jumpConfig})
+	if err == nil {
+		t.Errorf("expected error dialing unreachable target")
+	}
+}
@@ -0,0 +1,137 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// tlsDefaultPort is the default port of NETCONF over TLS (RFC 7589)
+const tlsDefaultPort = 6513
+
+// TransportTLS maintains the information necessary to communicate with the
+// remote device over TLS
+type TransportTLS struct {
+	TransportBasicIO
+	tlsConn *tls.Conn
+}
+
+// Close closes the TLS connection if it exists.
+func (t *TransportTLS) Close() error {
+	if t == nil || t.tlsConn == nil {
+		return fmt.Errorf("No connection to close")
+	}
+	return t.tlsConn.Close()
+}
+
+// Dial connects and completes the TLS handshake.
+//
+// target can be an IP address (e.g.) 172.16.1.1 which utlizes the default
+// NETCONF over TLS port of 6513.  Target can also specify a port with the
+// following format <host>:<port (e.g 172.16.1.1:6514)
+//
+// config usually sets the client certificate the device maps to a NETCONF
+// username, and the root CAs verifying the device certificate.
+func (t *TransportTLS) Dial(target string, config *tls.Config) error {
+	if !strings.Contains(target, ":") {
+		target = fmt.Sprintf("%s:%d", target, tlsDefaultPort)
+	}
+
+	conn, err := tls.Dial("tcp", target, config)
+	if err != nil {
+		return err
+	}
+
+	t.tlsConn = conn
+	t.ReadWriteCloser = conn
+	return nil
+}
+
+// DialTLS creates a new NETCONF session using a TLS Transport.
+// See TransportTLS.Dial for arguments.
+func DialTLS(target string, config *tls.Config) (*Session, error) {
+	var t TransportTLS
+	if err := t.Dial(target, config); err != nil {
+		sessionFailed(err)
+		return nil, err
+	}
+	return NewSession(&t), nil
+}
+
+// DialTLSContext creates a new NETCONF session using a TLS Transport, giving
+// up when ctx is done before the session is established.  The dial is traced
+// as a child of ctx.  See TransportTLS.Dial for arguments.
+func DialTLSContext(ctx context.Context, target string, config *tls.Config) (s *Session, err error) {
+	ctx, span := startSpan(ctx, DefaultTracer, SpanDial, Attribute{AttrTarget, target})
+	defer func() { endSpan(span, err) }()
+
+	if !strings.Contains(target, ":") {
+		target = fmt.Sprintf("%s:%d", target, tlsDefaultPort)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", target)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		sessionFailed(err)
+		return nil, err
+	}
+
+	// Bound the handshake and hello exchange by ctx
+	stop := watchDeadline(ctx, conn)
+	defer stop()
+
+	// Verify the device certificate against the host name like tls.Dial
+	if config == nil {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		config = config.Clone()
+		config.ServerName, _, _ = net.SplitHostPort(target)
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		sessionFailed(err)
+		return nil, err
+	}
+
+	t := &TransportTLS{tlsConn: tlsConn}
+	t.ReadWriteCloser = tlsConn
+	s = NewSessionContext(ctx, t)
+	stop()
+	if ctx.Err() != nil {
+		s.Close()
+		return nil, ctx.Err()
+	}
+	return s, nil
+}
+
+// NewTLSSession creates a new NETCONF session using an existing net.Conn,
+// config.ServerName being required unless InsecureSkipVerify is set.
+func NewTLSSession(conn net.Conn, config *tls.Config) (*Session, error) {
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		tlsConn.Close()
+		sessionFailed(err)
+		return nil, err
+	}
+
+	t := &TransportTLS{tlsConn: tlsConn}
+	t.ReadWriteCloser = tlsConn
+	return NewSession(t), nil
+}
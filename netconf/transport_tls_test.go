@@ -0,0 +1,162 @@
+// Go NETCONF Client
+//
+// Copyright (c) 2013-2018, Juniper Networks, Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netconf
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestCertificate returns a self-signed certificate for 127.0.0.1 and the
+// pool trusting it
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "netconf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// newTestTLSServer starts a NETCONF over TLS server on the loopback
+// interface
+func newTestTLSServer(t *testing.T, cert tls.Certificate) net.Listener {
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestNetconf(c)
+		}
+	}()
+	return l
+}
+
+func TestDialTLS(t *testing.T) {
+	cert, pool := newTestCertificate(t)
+	l := newTestTLSServer(t, cert)
+	defer l.Close()
+
+	s, err := DialTLS(l.Addr().String(), &tls.Config{RootCAs: pool})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	if s.SessionID != 42 {
+		t.Errorf("got session-id %d, expected 42", s.SessionID)
+	}
+	if _, err := s.Exec(MethodLock("candidate")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDialTLSUnknownAuthority(t *testing.T) {
+	cert, _ := newTestCertificate(t)
+	l := newTestTLSServer(t, cert)
+	defer l.Close()
+
+	if _, err := DialTLS(l.Addr().String(), &tls.Config{}); err == nil {
+		t.Errorf("expected certificate verification error")
+	}
+}
+
+func TestDialTLSContext(t *testing.T) {
+	cert, pool := newTestCertificate(t)
+	l := newTestTLSServer(t, cert)
+	defer l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := DialTLSContext(ctx, l.Addr().String(), &tls.Config{RootCAs: pool})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	if s.SessionID != 42 {
+		t.Errorf("got session-id %d, expected 42", s.SessionID)
+	}
+
+	// ctx no longer bounds the established session
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if _, err := s.Exec(MethodLock("candidate")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// The handshake gives up when ctx is done
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer silent.Close()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := DialTLSContext(ctx, silent.Addr().String(), &tls.Config{RootCAs: pool}); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestNewTLSSession(t *testing.T) {
+	cert, pool := newTestCertificate(t)
+	l := newTestTLSServer(t, cert)
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	s, err := NewTLSSession(conn, &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Exec(MethodUnlock("candidate")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}